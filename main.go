@@ -2,9 +2,17 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	"general_purpose_program/utils"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -39,7 +47,7 @@ func showAboutDialog() {
 	email := widget.NewLabel("github: https://github.com/Mybhsy/General_purpose_program")
 
 	// 创建描述信息
-	description := widget.NewLabel("这是一个实用的工具软件，集成了以下功能：\n\n1. 文件批量重命名：\n   - 支持通过Excel表格配置新旧文件名，轻松完成大量文件的重命名操作\n   - 适用于批量整理照片、文档、音视频等各类文件\n   - 操作简单，只需准备Excel文件和选择目标文件夹即可完成\n\n2. 文字转语音：\n   - 基于微软Edge TTS引擎，提供专业级语音合成服务\n   - 支持中文、英文、日文等多种语言，可选择不同性别和风格的发音人\n   - 语速调节范围-100%至+100%，满足不同场景需求\n   - 音量可调节，确保输出音频清晰舒适\n   - 支持Excel表格批量导入文本，自动转换并保存为MP3格式\n   - 适用于配音、教学、有声书制作等多种应用场景")
+	description := widget.NewLabel("这是一个实用的工具软件，集成了以下功能：\n\n1. 文件批量重命名：\n   - 支持通过Excel表格配置新旧文件名，轻松完成大量文件的重命名操作\n   - 新文件名可由多列按模板组合，如 {B}_{C:03}_{D}.{ext}，省略扩展名时自动保留原扩展名\n   - 也可使用CSV、TSV、ODS、JSON或YAML格式的表格\n   - 适用于批量整理照片、文档、音视频等各类文件\n   - 操作简单，只需准备Excel文件和选择目标文件夹即可完成\n   - 同一份表格可一次应用到多个文件夹（可用通配符选择，如 release/*），每个文件夹要么全部改名成功，要么保持原样\n   - 文件被其他程序短暂占用时自动等待重试，仍失败的行可在结果中一键重试\n   - 也可不用表格，按规则（正则替换、前后缀、大小写、编号、汉字转拼音、简繁转换）批量重命名并实时预览\n   - 表格中只有新文件名时，可按文件名、修改时间或拍摄时间的顺序与文件逐个配对\n   - 支持按模板使用照片EXIF、MP3标签、修改时间、大小和哈希生成文件名\n   - 可监视文件夹，新放入的文件写完后自动按表格或规则改名，也可用 -watch 参数在命令行中运行\n\n2. 文字转语音：\n   - 基于微软Edge TTS引擎，提供专业级语音合成服务\n   - 支持中文、英文、日文等多种语言，可选择不同性别和风格的发音人\n   - 语速调节范围-100%至+100%，满足不同场景需求\n   - 音量可调节，确保输出音频清晰舒适\n   - 支持Excel表格批量导入文本，自动转换并保存为MP3格式，文件名可转为拼音\n   - 适用于配音、教学、有声书制作等多种应用场景")
 	description.Wrapping = fyne.TextWrapWord
 
 	// 创建可滚动的内容区域
@@ -65,7 +73,24 @@ func showAboutDialog() {
 	dialog.Show()
 }
 
+// 无界面监视模式的命令行参数，指定 -watch 时不打开窗口
+var (
+	watchFolderFlag    = flag.String("watch", "", "无界面监视该文件夹，文件写完后自动改名")
+	watchTableFlag     = flag.String("table", "", "监视时使用的映射表格")
+	watchRulesFlag     = flag.String("rules", "", "监视时使用的规则文件（规则链保存的 JSON）")
+	watchStableFlag    = flag.Duration("stable", utils.DefaultStableDuration, "文件大小保持不变多久后才处理")
+	watchExistingFlag  = flag.Bool("existing", false, "启动时也处理文件夹中已有的文件")
+	watchCollisionFlag = flag.String("collision", utils.CollisionSkip.String(), "目标已存在时的处理方式")
+	watchRetriesFlag   = flag.Int("retries", utils.DefaultRenameRetries, "文件被其他进程占用时每个文件最多重试的次数")
+	watchLogFlag       = flag.String("log", "", "日志文件，默认写入配置目录下的 watch.log")
+)
+
 func main() {
+	flag.Parse()
+	if *watchFolderFlag != "" {
+		os.Exit(runHeadlessWatch())
+	}
+
 	// 创建应用
 	myApp := app.New()
 	
@@ -79,6 +104,7 @@ func main() {
 	tabs := container.NewAppTabs(
 		container.NewTabItem("文件重命名", createRenameTab()),
 		container.NewTabItem("文字转语音", createTTSTab()),
+		container.NewTabItem("监视文件夹", createWatchTab()),
 	)
 	
 	// 设置标签页位置
@@ -98,10 +124,12 @@ func main() {
 func createRenameTab() fyne.CanvasObject {
 	// 状态变量
 	var excelPath, folderPath string
+	var mapping utils.ColumnMapping
+	var options utils.RenameOptions
 	statusLabel := widget.NewLabel("准备就绪")
 
-	// Excel文件选择按钮
-	selectExcelBtn := widget.NewButton("选择Excel文件", func() {
+	// 表格文件选择按钮（xlsx、csv、tsv、ods、json、yaml）
+	selectExcelBtn := widget.NewButton("选择表格文件", func() {
 		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
 			if err != nil {
 				dialog.ShowError(err, window)
@@ -111,12 +139,129 @@ func createRenameTab() fyne.CanvasObject {
 				return
 			}
 			excelPath = reader.URI().Path()
-			statusLabel.SetText("已选择Excel文件: " + excelPath)
+			selectTableMapping(excelPath, utils.MappingRename, statusLabel, func(m utils.ColumnMapping) { mapping = m })
 		}, window)
-		fd.SetFilter(storage.NewExtensionFileFilter([]string{".xlsx"}))
+		fd.SetFilter(storage.NewExtensionFileFilter(utils.TableExtensions()))
 		fd.Show()
 	})
 
+	// 导出文件清单按钮
+	exportListBtn := widget.NewButton("导出文件清单", func() {
+		if folderPath == "" {
+			dialog.ShowError(errors.New("请先选择目标文件夹"), window)
+			return
+		}
+		showExportFileListDialog(folderPath, statusLabel)
+	})
+	// 列映射按钮：选择工作表以及新旧文件名所在的列
+	mappingBtn := widget.NewButton("列映射", func() {
+		if excelPath == "" {
+			dialog.ShowError(errors.New("请先选择表格文件"), window)
+			return
+		}
+		showColumnMappingDialog(excelPath, utils.MappingRename, mapping, func(m utils.ColumnMapping) {
+			mapping = m
+			statusLabel.SetText("已选择表格文件: " + excelPath + "\n" + mapping.Describe(utils.MappingRename))
+		})
+	})
+	excelRow := container.NewGridWithColumns(3, selectExcelBtn, mappingBtn, exportListBtn)
+
+	// 按规则或按模板生成新文件名时的实时预览
+	var generated generatedRename
+	var preview []utils.ExcelData
+	var previewKeys []string
+	var previewMu sync.Mutex
+	var previewSeq int
+	previewLabel := widget.NewLabel("")
+	previewLabel.Wrapping = fyne.TextWrapWord
+	previewTable := newStringTable(
+		[]string{"原文件名", "新文件名"},
+		[]float32{220, 220},
+		func() int {
+			previewMu.Lock()
+			defer previewMu.Unlock()
+			return len(preview)
+		},
+		func(row, col int) string {
+			previewMu.Lock()
+			defer previewMu.Unlock()
+			if row >= len(preview) {
+				return ""
+			}
+			switch {
+			case col == 0 && row < len(previewKeys) && previewKeys[row] != "":
+				return preview[row].OldName + "（" + previewKeys[row] + "）"
+			case col == 0:
+				return preview[row].OldName
+			case preview[row].Problem != "" && preview[row].NewName != "":
+				return preview[row].NewName + "（" + preview[row].Problem + "）"
+			case preview[row].Problem != "":
+				return "无法生成: " + preview[row].Problem
+			default:
+				return preview[row].NewName
+			}
+		},
+	)
+	refreshPreview := func() {
+		g := generated
+		g.folderPath = folderPath
+		previewMu.Lock()
+		previewSeq++
+		seq := previewSeq
+		previewMu.Unlock()
+
+		// 读取元数据或计算哈希可能较慢，在后台生成，只显示最后一次修改的结果
+		go func() {
+			data, keys, warning, err := g.build()
+			previewMu.Lock()
+			if seq != previewSeq {
+				previewMu.Unlock()
+				return
+			}
+			preview, previewKeys = data, keys
+			previewMu.Unlock()
+
+			if err != nil {
+				previewLabel.SetText(err.Error())
+			} else {
+				changed, problems := 0, 0
+				for _, row := range data {
+					if row.Problem != "" {
+						problems++
+					} else if row.OldName != row.NewName {
+						changed++
+					}
+				}
+				summary := fmt.Sprintf("共 %d 个文件，其中 %d 个将被重命名", len(data), changed)
+				if problems > 0 {
+					summary += fmt.Sprintf("，%d 个无法生成文件名", problems)
+				}
+				if warning != "" {
+					summary += "\n" + warning
+				}
+				previewLabel.SetText(summary)
+			}
+			previewTable.Refresh()
+		}()
+	}
+	ruleEditor := createRuleEditor(func(chain utils.RuleChain) {
+		generated.chain = chain
+		refreshPreview()
+	})
+	templateEditor := createTemplateEditor(func(template, filter string) {
+		generated.template, generated.filter = template, filter
+		refreshPreview()
+	})
+	orderEditor := createOrderEditor(func(table string, mapping utils.ColumnMapping, order utils.PairOrder, filter string) {
+		generated.orderTable, generated.orderMapping, generated.order, generated.orderFilter = table, mapping, order, filter
+		refreshPreview()
+	})
+	generatedPanel := container.NewHSplit(
+		container.NewStack(ruleEditor, templateEditor, orderEditor),
+		container.NewBorder(previewLabel, nil, nil, nil, previewTable),
+	)
+	generatedPanel.Offset = 0.4
+
 	// 目标文件夹选择按钮
 	selectFolderBtn := widget.NewButton("选择目标文件夹", func() {
 		fd := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
@@ -127,57 +272,1246 @@ func createRenameTab() fyne.CanvasObject {
 			if uri == nil {
 				return
 			}
-			folderPath = uri.Path()
-			statusLabel.SetText("已选择目标文件夹: " + folderPath)
+			folderPath = uri.Path()
+			statusLabel.SetText("已选择目标文件夹: " + folderPath)
+			if generated.mode != renameModeExcel {
+				refreshPreview()
+			}
+		}, window)
+		fd.Show()
+	})
+
+	// 复制模式：保留原文件，把重命名后的副本写入输出文件夹
+	var outputFolder string
+	copyCheck := widget.NewCheck("保留原文件，把副本写入输出文件夹", func(checked bool) {
+		options.Output = ""
+		if checked {
+			options.Output = outputFolder
+		}
+	})
+	outputFolderBtn := widget.NewButton("选择输出文件夹", func() {
+		fd := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if uri == nil {
+				return
+			}
+			outputFolder = uri.Path()
+			if copyCheck.Checked {
+				options.Output = outputFolder
+			}
+			statusLabel.SetText("已选择输出文件夹: " + outputFolder)
+		}, window)
+		fd.Show()
+	})
+	copyMethodOptions := make([]string, len(utils.CopyMethods))
+	for i, method := range utils.CopyMethods {
+		copyMethodOptions[i] = method.String()
+	}
+	copyMethodSelect := widget.NewSelect(copyMethodOptions, func(value string) {
+		if method, err := utils.ParseCopyMethod(value); err == nil {
+			options.Copy = method
+		}
+	})
+	copyMethodSelect.SetSelected(utils.CopyFull.String())
+
+	// 开始重命名按钮
+	startRenameBtn := widget.NewButton("预览并重命名", func() {
+		if copyCheck.Checked && outputFolder == "" {
+			dialog.ShowError(errors.New("复制模式下请先选择输出文件夹"), window)
+			return
+		}
+		if generated.mode != renameModeExcel {
+			g := generated
+			g.folderPath = folderPath
+			startGeneratedRename(g, options, statusLabel, refreshPreview)
+			return
+		}
+		if excelPath == "" || folderPath == "" {
+			dialog.ShowError(errors.New("请先选择表格文件和目标文件夹"), window)
+			return
+		}
+
+		go func() {
+			// 边读取表格边检查每一行，读完后再检查行与行之间的冲突
+			statusLabel.SetText("正在读取表格文件...")
+			plan, err := utils.PlanRenameTable(folderPath, excelPath, mapping, options, readProgress(statusLabel))
+			if err != nil {
+				dialog.ShowError(err, window)
+				statusLabel.SetText("生成重命名计划失败")
+				return
+			}
+
+			statusLabel.SetText("请确认重命名计划: " + plan.Summary())
+			showRenamePlanDialog(plan, func() {
+				go runRename(plan, excelPath, statusLabel)
+			})
+		}()
+	})
+
+	// 应用到多个文件夹按钮：同一份表格依次应用到多个文件夹，如各语言版本的素材文件夹
+	multiFolderBtn := widget.NewButton("应用到多个文件夹", func() {
+		if copyCheck.Checked && outputFolder == "" {
+			dialog.ShowError(errors.New("复制模式下请先选择输出文件夹"), window)
+			return
+		}
+		if excelPath == "" {
+			dialog.ShowError(errors.New("请先选择表格文件"), window)
+			return
+		}
+		showMultiFolderDialog(excelPath, mapping, options, statusLabel)
+	})
+
+	// 目标冲突处理方式
+	policyOptions := make([]string, len(utils.CollisionPolicies))
+	for i, policy := range utils.CollisionPolicies {
+		policyOptions[i] = policy.String()
+	}
+	collisionSelect := widget.NewSelect(policyOptions, func(value string) {
+		if policy, err := utils.ParseCollisionPolicy(value); err == nil {
+			options.Collision = policy
+		}
+	})
+	collisionSelect.SetSelected(utils.CollisionSkip.String())
+
+	// 原文件名的匹配方式
+	matchOptions := make([]string, len(utils.MatchModes))
+	for i, mode := range utils.MatchModes {
+		matchOptions[i] = mode.String()
+	}
+	matchSelect := widget.NewSelect(matchOptions, func(value string) {
+		if mode, err := utils.ParseMatchMode(value); err == nil {
+			options.Match = mode
+		}
+	})
+	matchSelect.SetSelected(utils.MatchExact.String())
+	matchLabel := widget.NewLabel("原文件名匹配")
+
+	// 在子文件夹中查找原文件
+	recursiveCheck := widget.NewCheck("在子文件夹中查找原文件（表格中可使用“子文件夹/文件名”形式的相对路径）", func(checked bool) {
+		options.Recursive = checked
+	})
+
+	// 新文件名需要兼容的目标平台，以及是否自动修正不兼容的文件名
+	platformOptions := make([]string, len(utils.TargetPlatforms))
+	for i, platform := range utils.TargetPlatforms {
+		platformOptions[i] = platform.String()
+	}
+	platformGroup := widget.NewCheckGroup(platformOptions, func(selected []string) {
+		options.Platforms = nil
+		for _, value := range selected {
+			if platform, err := utils.ParseTargetPlatform(value); err == nil {
+				options.Platforms = append(options.Platforms, platform)
+			}
+		}
+	})
+	platformGroup.Horizontal = true
+	sanitizeCheck := widget.NewCheck("自动修正不兼容的文件名", func(checked bool) {
+		options.Sanitize = checked
+	})
+
+	// 撤销上次重命名按钮
+	undoLastBtn := widget.NewButton("撤销上次重命名", func() {
+		journal, err := utils.LastRenameJournal()
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		confirmUndoRename(journal, statusLabel)
+	})
+
+	// 重命名历史按钮
+	historyBtn := widget.NewButton("重命名历史", func() {
+		showRenameHistoryDialog(statusLabel)
+	})
+
+	// 从备份文件恢复按钮，用于历史记录已丢失或备份被复制到其他电脑的情况
+	restoreBackupBtn := widget.NewButton("从备份文件恢复", func() {
+		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			path := reader.URI().Path()
+			reader.Close()
+			confirmRestoreBackup(path, statusLabel)
+		}, window)
+		fd.SetFilter(storage.NewExtensionFileFilter([]string{".zip"}))
+		if dir, err := utils.RenameBackupDir(); err == nil {
+			if lister, err := storage.ListerForURI(storage.NewFileURI(dir)); err == nil {
+				fd.SetLocation(lister)
+			}
+		}
+		fd.Show()
+	})
+
+	// 执行前备份方式
+	backupOptions := make([]string, len(utils.BackupModes))
+	for i, mode := range utils.BackupModes {
+		backupOptions[i] = mode.String()
+	}
+	backupSelect := widget.NewSelect(backupOptions, func(value string) {
+		if mode, err := utils.ParseBackupMode(value); err == nil {
+			options.Backup = mode
+		}
+	})
+	backupSelect.SetSelected(utils.BackupNone.String())
+
+	// 文件被其他进程占用时的重试次数
+	retryCounts := []int{0, utils.DefaultRenameRetries, 5, 10}
+	retryOptions := make([]string, len(retryCounts))
+	for i, count := range retryCounts {
+		retryOptions[i] = retryCountLabel(count)
+	}
+	retrySelect := widget.NewSelect(retryOptions, func(value string) {
+		for _, count := range retryCounts {
+			if retryCountLabel(count) == value {
+				options.Retries = count
+			}
+		}
+	})
+	retrySelect.SetSelected(retryCountLabel(utils.DefaultRenameRetries))
+
+	// 重命名方式：按表格、按规则、按模板或按顺序
+	modeRadio := widget.NewRadioGroup([]string{renameModeExcel, renameModeRules, renameModeTemplate, renameModeOrder}, func(value string) {
+		generated.mode = value
+		if value == renameModeExcel {
+			excelRow.Show()
+			multiFolderBtn.Show()
+			matchLabel.Show()
+			matchSelect.Show()
+			recursiveCheck.Show()
+			generatedPanel.Hide()
+			return
+		}
+		excelRow.Hide()
+		multiFolderBtn.Hide()
+		matchLabel.Hide()
+		matchSelect.Hide()
+		recursiveCheck.Hide()
+		ruleEditor.Hide()
+		templateEditor.Hide()
+		orderEditor.Hide()
+		switch value {
+		case renameModeRules:
+			ruleEditor.Show()
+		case renameModeTemplate:
+			templateEditor.Show()
+		default:
+			orderEditor.Show()
+		}
+		generatedPanel.Show()
+		refreshPreview()
+	})
+	modeRadio.Horizontal = true
+	modeRadio.Required = true
+	modeRadio.SetSelected(renameModeExcel)
+
+	// 布局
+	top := container.NewVBox(
+		modeRadio,
+		excelRow,
+		selectFolderBtn,
+		container.NewGridWithColumns(2,
+			widget.NewLabel("目标冲突时"),
+			collisionSelect,
+			matchLabel,
+			matchSelect,
+			widget.NewLabel("执行前备份"),
+			backupSelect,
+			widget.NewLabel("文件被占用时"),
+			retrySelect,
+		),
+		recursiveCheck,
+		container.NewGridWithColumns(3, copyCheck, outputFolderBtn, copyMethodSelect),
+		container.NewHBox(widget.NewLabel("兼容平台"), platformGroup, sanitizeCheck),
+	)
+	bottom := container.NewVBox(
+		container.NewBorder(nil, nil, nil, multiFolderBtn, startRenameBtn),
+		container.NewGridWithColumns(3, undoLastBtn, historyBtn, restoreBackupBtn),
+		statusLabel,
+	)
+	return container.NewBorder(top, bottom, nil, nil, generatedPanel)
+}
+
+// 重命名方式
+const (
+	renameModeExcel    = "按表格"
+	renameModeRules    = "按规则"
+	renameModeTemplate = "按模板"
+	renameModeOrder    = "按顺序"
+)
+
+// generatedRename 按规则、按模板或按顺序生成新文件名时的设置
+type generatedRename struct {
+	folderPath   string
+	mode         string
+	chain        utils.RuleChain
+	template     string
+	filter       string
+	orderTable   string
+	orderMapping utils.ColumnMapping
+	order        utils.PairOrder
+	orderFilter  string
+}
+
+// build 为目标文件夹中的文件生成新文件名，按顺序配对时同时返回每个文件的排序依据和数量不一致的提示
+func (g generatedRename) build() ([]utils.ExcelData, []string, string, error) {
+	var data []utils.ExcelData
+	var err error
+	switch {
+	case g.folderPath == "":
+		err = errors.New("请先选择目标文件夹")
+	case g.mode == renameModeRules && len(g.chain.Rules) == 0:
+		err = errors.New("请在左侧添加重命名规则")
+	case g.mode == renameModeRules:
+		data, err = utils.BuildRuleRenames(g.folderPath, g.chain)
+	case g.mode == renameModeOrder && g.orderTable == "":
+		err = errors.New("请在左侧选择只有新文件名的表格")
+	case g.mode == renameModeOrder:
+		names, err := utils.ReadOrderTable(g.orderTable, g.orderMapping)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		pairing, err := utils.BuildOrderRenames(g.folderPath, g.orderFilter, g.order, names)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		return pairing.Data, pairing.Keys, pairing.Warning(), nil
+	case strings.TrimSpace(g.template) == "":
+		err = errors.New("请在左侧输入文件名模板")
+	default:
+		data, err = utils.BuildTemplateRenames(g.folderPath, g.template, g.filter)
+	}
+	return data, nil, "", err
+}
+
+// showExportFileListDialog 选择附加列后把目标文件夹的文件清单导出为重命名表格
+func showExportFileListDialog(folderPath string, statusLabel *widget.Label) {
+	var options utils.FileListOptions
+	checks := container.NewVBox(
+		widget.NewCheck("包含子文件夹中的文件", func(checked bool) { options.Recursive = checked }),
+		widget.NewCheck("附加文件大小列", func(checked bool) { options.Size = checked }),
+		widget.NewCheck("附加修改时间列", func(checked bool) { options.ModTime = checked }),
+		widget.NewCheck("附加扩展名列", func(checked bool) { options.Extension = checked }),
+	)
+	dialog.ShowCustomConfirm("导出文件清单", "选择保存位置", "取消", checks, func(ok bool) {
+		if !ok {
+			return
+		}
+		fd := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			outputPath := writer.URI().Path()
+			writer.Close()
+			go func() {
+				statusLabel.SetText("正在导出文件清单...")
+				count, err := utils.ExportFileList(folderPath, outputPath, options)
+				if err != nil {
+					dialog.ShowError(err, window)
+					statusLabel.SetText("导出文件清单失败")
+					return
+				}
+				statusLabel.SetText(fmt.Sprintf("已导出 %d 个文件到 %s", count, outputPath))
+				dialog.ShowInformation("成功", fmt.Sprintf("已导出 %d 个文件，在“新文件名”列中修改后即可用于重命名", count), window)
+			}()
+		}, window)
+		fd.SetFileName("文件清单.xlsx")
+		fd.SetFilter(storage.NewExtensionFileFilter([]string{".xlsx"}))
+		fd.Show()
+	}, window)
+}
+
+// selectTableMapping 选择表格文件后确定列映射：没有记住的设置时，
+// 如果无法识别标题或工作簿有多个工作表，就打开列映射对话框让用户确认
+func selectTableMapping(path, purpose string, statusLabel *widget.Label, onMapping func(mapping utils.ColumnMapping)) {
+	mapping, source, err := utils.AutoColumnMapping(path, purpose)
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	onMapping(mapping)
+	statusLabel.SetText("已选择表格文件: " + path + "\n" + mapping.Describe(purpose))
+	if source == utils.MappingRemembered {
+		return
+	}
+	sheets, _ := utils.TableSheets(path)
+	if source == utils.MappingDefault || len(sheets) > 1 {
+		showColumnMappingDialog(path, purpose, mapping, func(mapping utils.ColumnMapping) {
+			onMapping(mapping)
+			statusLabel.SetText("已选择表格文件: " + path + "\n" + mapping.Describe(purpose))
+		})
+	}
+}
+
+// mappingPreviewRows 列映射对话框读取的行数，大表格只读开头部分用于识别标题和预览
+const mappingPreviewRows = 50
+
+// showColumnMappingDialog 选择工作表、标题行以及各字段所在的列，可以为该工作簿记住设置
+func showColumnMappingDialog(path, purpose string, current utils.ColumnMapping, onDone func(mapping utils.ColumnMapping)) {
+	sheets, err := utils.TableSheets(path)
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	mapping := current
+	var rows [][]string
+
+	// 需要映射的字段，可选的字段可以设为不使用
+	type mappingField struct {
+		label    string
+		column   *int
+		optional bool
+	}
+	fields := []mappingField{
+		{"原文件名所在列", &mapping.OldName, false},
+		{"新文件名所在列", &mapping.NewName, false},
+		{"操作所在列", &mapping.Operation, true},
+	}
+	if purpose == utils.MappingTTS {
+		fields = []mappingField{{"文本所在列", &mapping.Text, false}}
+	}
+	if purpose == utils.MappingOrder {
+		fields = []mappingField{{"新文件名所在列", &mapping.NewName, false}}
+	}
+	const noColumn = "（不使用）"
+
+	// 重命名时可用模板由多列组合出新文件名
+	composeNames := purpose == utils.MappingRename
+	var composer *utils.NewNameComposer
+	templateError := ""
+	if composeNames && mapping.NewNameTemplate != "" {
+		composer, err = utils.ParseNewNameTemplate(mapping.NewNameTemplate)
+		if err != nil {
+			templateError = err.Error()
+		}
+	}
+
+	// 预览按当前映射读取到的前 20 行
+	var previewRows [][]string
+	headers := []string{"行号"}
+	widths := []float32{60}
+	for _, field := range fields {
+		headers = append(headers, strings.TrimSuffix(field.label, "所在列"))
+		widths = append(widths, 240)
+	}
+	if composeNames {
+		headers = append(headers, "组合的新文件名")
+		widths = append(widths, 240)
+	}
+	previewTable := newStringTable(headers, widths,
+		func() int { return len(previewRows) },
+		func(row, col int) string { return previewRows[row][col] },
+	)
+	refreshPreview := func() {
+		previewRows = nil
+		for i := mapping.HeaderRow; i < len(rows) && len(previewRows) < 20; i++ {
+			line := []string{fmt.Sprint(i + 1)}
+			for _, field := range fields {
+				if *field.column >= 0 && *field.column < len(rows[i]) {
+					line = append(line, rows[i][*field.column])
+				} else {
+					line = append(line, "")
+				}
+			}
+			if composeNames {
+				switch {
+				case templateError != "":
+					line = append(line, templateError)
+				case composer == nil:
+					line = append(line, "（未使用模板）")
+				default:
+					oldName := ""
+					if mapping.OldName >= 0 && mapping.OldName < len(rows[i]) {
+						oldName = strings.TrimSpace(rows[i][mapping.OldName])
+					}
+					if name, err := composer.Compose(rows[i], oldName); err != nil {
+						line = append(line, "无法生成: "+err.Error())
+					} else {
+						line = append(line, name)
+					}
+				}
+			}
+			previewRows = append(previewRows, line)
+		}
+		previewTable.Refresh()
+	}
+
+	// 列选项显示列名和标题行中的文字，如“B 列（新文件名）”
+	var columnOptions []string
+	columnSelects := make([]*widget.Select, len(fields))
+	for i, field := range fields {
+		field := field
+		columnSelects[i] = widget.NewSelect(nil, func(value string) {
+			if value == noColumn {
+				*field.column = -1
+			}
+			for col, option := range columnOptions {
+				if option == value {
+					*field.column = col
+				}
+			}
+			refreshPreview()
+		})
+	}
+	refreshColumns := func() {
+		width := 0
+		for i := 0; i < len(rows) && i < 50; i++ {
+			if len(rows[i]) > width {
+				width = len(rows[i])
+			}
+		}
+		for _, field := range fields {
+			if *field.column >= width {
+				width = *field.column + 1
+			}
+		}
+		columnOptions = make([]string, width)
+		for col := range columnOptions {
+			columnOptions[col] = utils.ColumnName(col) + " 列"
+			if mapping.HeaderRow > 0 && mapping.HeaderRow <= len(rows) && col < len(rows[mapping.HeaderRow-1]) {
+				if header := strings.TrimSpace(rows[mapping.HeaderRow-1][col]); header != "" {
+					columnOptions[col] += "（" + header + "）"
+				}
+			}
+		}
+		for i, field := range fields {
+			columnSelects[i].Options = columnOptions
+			if field.optional {
+				columnSelects[i].Options = append([]string{noColumn}, columnOptions...)
+			}
+			switch {
+			case *field.column >= 0:
+				columnSelects[i].SetSelected(columnOptions[*field.column])
+			case field.optional:
+				columnSelects[i].SetSelected(noColumn)
+			default:
+				columnSelects[i].ClearSelected()
+			}
+		}
+		refreshPreview()
+	}
+
+	headerEntry := widget.NewEntry()
+	headerEntry.SetText(strconv.Itoa(mapping.HeaderRow))
+	headerEntry.OnChanged = func(value string) {
+		if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && n >= 0 {
+			mapping.HeaderRow = n
+			refreshColumns()
+		}
+	}
+
+	errorLabel := widget.NewLabel("")
+	errorLabel.Wrapping = fyne.TextWrapWord
+	loadSheet := func(detect bool) {
+		rows, err = utils.ReadTableHead(path, mapping.Sheet, mappingPreviewRows)
+		if err != nil {
+			rows = nil
+			errorLabel.SetText(err.Error())
+		} else {
+			errorLabel.SetText("")
+		}
+		if detected, ok := utils.DetectColumnMapping(rows, purpose); detect && ok {
+			detected.Sheet, detected.NewNameTemplate = mapping.Sheet, mapping.NewNameTemplate
+			mapping = detected
+			headerEntry.SetText(strconv.Itoa(mapping.HeaderRow))
+		}
+		refreshColumns()
+	}
+
+	form := widget.NewForm()
+	if len(sheets) > 1 {
+		sheetSelect := widget.NewSelect(sheets, nil)
+		if mapping.Sheet == "" {
+			sheetSelect.SetSelected(sheets[0])
+		} else {
+			sheetSelect.SetSelected(mapping.Sheet)
+		}
+		sheetSelect.OnChanged = func(value string) {
+			mapping.Sheet = value
+			loadSheet(true)
+		}
+		form.Append("工作表", sheetSelect)
+	}
+	form.Append("标题行（0 表示没有）", headerEntry)
+	for i, field := range fields {
+		form.Append(field.label, columnSelects[i])
+	}
+	if composeNames {
+		templateEntry := widget.NewEntry()
+		templateEntry.SetPlaceHolder("{B}_{C:03}_{D}.{ext}，留空时直接使用新文件名所在的列")
+		templateEntry.SetText(mapping.NewNameTemplate)
+		templateEntry.OnChanged = func(value string) {
+			mapping.NewNameTemplate = strings.TrimSpace(value)
+			composer, templateError = nil, ""
+			if mapping.NewNameTemplate != "" {
+				if composer, err = utils.ParseNewNameTemplate(mapping.NewNameTemplate); err != nil {
+					templateError = err.Error()
+				}
+			}
+			refreshPreview()
+		}
+		form.Append("新文件名模板（可选）", templateEntry)
+	}
+	remember := widget.NewCheck("为该文件记住此设置", nil)
+	remember.SetChecked(true)
+	loadSheet(false)
+
+	content := container.NewBorder(container.NewVBox(form, remember, errorLabel), nil, nil, nil, previewTable)
+	d := dialog.NewCustomConfirm("列映射", "确定", "取消", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := mapping.Validate(purpose); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if remember.Checked {
+			if err := utils.SaveColumnMapping(path, purpose, mapping); err != nil {
+				dialog.ShowError(err, window)
+			}
+		}
+		onDone(mapping)
+	}, window)
+	d.Resize(fyne.NewSize(700, 560))
+	d.Show()
+}
+
+// startGeneratedRename 按规则或模板生成重命名计划，确认后执行并刷新预览
+func startGeneratedRename(g generatedRename, options utils.RenameOptions, statusLabel *widget.Label, onDone func()) {
+	go func() {
+		statusLabel.SetText("正在生成新文件名...")
+		data, _, warning, err := g.build()
+		if err != nil {
+			dialog.ShowError(err, window)
+			statusLabel.SetText("生成新文件名失败")
+			return
+		}
+
+		// 规则、模板和按顺序配对只处理目标文件夹本身的文件，文件名取自实际目录，不需要在子文件夹中查找或模糊匹配
+		options.Recursive = false
+		options.Match = utils.MatchExact
+		showPlan := func() {
+			plan, err := utils.PlanRename(g.folderPath, data, options)
+			if err != nil {
+				dialog.ShowError(err, window)
+				statusLabel.SetText("生成重命名计划失败")
+				return
+			}
+
+			statusLabel.SetText("请确认重命名计划: " + plan.Summary())
+			showRenamePlanDialog(plan, func() {
+				go func() {
+					runRename(plan, "", statusLabel)
+					onDone()
+				}()
+			})
+		}
+
+		// 按顺序配对时数量不一致，多半是表格或文件夹选错了，先让用户确认
+		if warning != "" {
+			statusLabel.SetText(warning)
+			dialog.ShowConfirm("数量不一致", warning+"\n\n仍要继续吗？", func(ok bool) {
+				if ok {
+					showPlan()
+				}
+			}, window)
+			return
+		}
+		showPlan()
+	}()
+}
+
+// createRuleEditor 创建重命名规则编辑区，规则或文件筛选条件变化时调用 onChange
+func createRuleEditor(onChange func(chain utils.RuleChain)) fyne.CanvasObject {
+	var chain utils.RuleChain
+	selected := -1
+
+	// 已添加的规则列表
+	ruleList := widget.NewList(
+		func() int { return len(chain.Rules) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(fmt.Sprintf("%d. %s", id+1, chain.Rules[id].Describe()))
+		},
+	)
+	ruleList.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+	ruleList.OnUnselected = func(id widget.ListItemID) {
+		selected = -1
+	}
+	changed := func() {
+		selected = -1
+		ruleList.UnselectAll()
+		ruleList.Refresh()
+		onChange(chain)
+	}
+
+	// 各类规则的参数
+	findEntry := widget.NewEntry()
+	findEntry.SetPlaceHolder("查找内容")
+	replaceEntry := widget.NewEntry()
+	replaceEntry.SetPlaceHolder("替换为（正则模式下可用 $1 引用分组）")
+	regexCheck := widget.NewCheck("使用正则表达式", nil)
+	extensionCheck := widget.NewCheck("同时作用于扩展名", nil)
+	textEntry := widget.NewEntry()
+	textEntry.SetPlaceHolder("要添加的文本")
+
+	caseNames := []string{"全部大写", "全部小写", "首字母大写"}
+	caseValues := map[string]string{"全部大写": utils.CaseUpper, "全部小写": utils.CaseLower, "首字母大写": utils.CaseTitle}
+	caseSelect := widget.NewSelect(caseNames, nil)
+	caseSelect.SetSelected("全部小写")
+
+	startEntry := widget.NewEntry()
+	startEntry.SetText("1")
+	stepEntry := widget.NewEntry()
+	stepEntry.SetText("1")
+	widthEntry := widget.NewEntry()
+	widthEntry.SetText("3")
+	separatorEntry := widget.NewEntry()
+	separatorEntry.SetText("_")
+	positionNames := []string{"放在前面", "放在后面", "替换文件名"}
+	positionValues := map[string]string{"放在前面": utils.NumberPrefix, "放在后面": utils.NumberSuffix, "替换文件名": utils.NumberReplace}
+	positionSelect := widget.NewSelect(positionNames, nil)
+	positionSelect.SetSelected("放在前面")
+	scriptOptions := make([]string, len(utils.ScriptConversions))
+	for i, conversion := range utils.ScriptConversions {
+		scriptOptions[i] = conversion.String()
+	}
+	scriptSelect := widget.NewSelect(scriptOptions, nil)
+	scriptSelect.SetSelected(utils.ConvertPinyin.String())
+	pinyinSeparatorEntry := widget.NewEntry()
+	pinyinSeparatorEntry.SetPlaceHolder("拼音之间的分隔符，如 _，留空时直接连写")
+	numberFields := container.NewGridWithColumns(2,
+		widget.NewLabel("起始值"), startEntry,
+		widget.NewLabel("步长"), stepEntry,
+		widget.NewLabel("位数"), widthEntry,
+		widget.NewLabel("分隔符"), separatorEntry,
+		widget.NewLabel("序号位置"), positionSelect,
+	)
+
+	// 规则类型切换时只显示对应的参数
+	fields := container.NewVBox()
+	ruleTypes := map[string]utils.RuleType{
+		"文本替换": utils.RuleReplace,
+		"添加前缀": utils.RulePrefix,
+		"添加后缀": utils.RuleSuffix,
+		"大小写":  utils.RuleCase,
+		"编号":   utils.RuleNumber,
+		"文字转换": utils.RuleScript,
+	}
+	typeSelect := widget.NewSelect([]string{"文本替换", "添加前缀", "添加后缀", "大小写", "编号", "文字转换"}, func(value string) {
+		switch ruleTypes[value] {
+		case utils.RuleReplace:
+			fields.Objects = []fyne.CanvasObject{findEntry, replaceEntry, regexCheck, extensionCheck}
+		case utils.RulePrefix, utils.RuleSuffix:
+			fields.Objects = []fyne.CanvasObject{textEntry}
+		case utils.RuleCase:
+			fields.Objects = []fyne.CanvasObject{caseSelect, extensionCheck}
+		case utils.RuleNumber:
+			fields.Objects = []fyne.CanvasObject{numberFields}
+		case utils.RuleScript:
+			fields.Objects = []fyne.CanvasObject{scriptSelect, pinyinSeparatorEntry, extensionCheck}
+		}
+		fields.Refresh()
+	})
+	typeSelect.SetSelected("文本替换")
+
+	// 添加规则按钮
+	addBtn := widget.NewButton("添加规则", func() {
+		rule := utils.RenameRule{Type: ruleTypes[typeSelect.Selected]}
+		switch rule.Type {
+		case utils.RuleReplace:
+			rule.Find = findEntry.Text
+			rule.Replace = replaceEntry.Text
+			rule.Regex = regexCheck.Checked
+			rule.Extension = extensionCheck.Checked
+		case utils.RulePrefix, utils.RuleSuffix:
+			if textEntry.Text == "" {
+				dialog.ShowError(errors.New("请输入要添加的文本"), window)
+				return
+			}
+			rule.Text = textEntry.Text
+		case utils.RuleCase:
+			rule.Case = caseValues[caseSelect.Selected]
+			rule.Extension = extensionCheck.Checked
+		case utils.RuleNumber:
+			numbers := []struct {
+				name  string
+				entry *widget.Entry
+				value *int
+			}{
+				{"起始值", startEntry, &rule.Start},
+				{"步长", stepEntry, &rule.Step},
+				{"位数", widthEntry, &rule.Width},
+			}
+			for _, n := range numbers {
+				value, err := strconv.Atoi(strings.TrimSpace(n.entry.Text))
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("%s必须是整数", n.name), window)
+					return
+				}
+				*n.value = value
+			}
+			rule.Separator = separatorEntry.Text
+			rule.Position = positionValues[positionSelect.Selected]
+		case utils.RuleScript:
+			conversion, err := utils.ParseScriptConversion(scriptSelect.Selected)
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			rule.Script = conversion
+			rule.Separator = pinyinSeparatorEntry.Text
+			rule.Extension = extensionCheck.Checked
+		}
+
+		candidate := chain
+		candidate.Rules = append(append([]utils.RenameRule{}, chain.Rules...), rule)
+		if err := candidate.Validate(); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		chain = candidate
+		changed()
+	})
+
+	// 删除所选规则和清空规则按钮
+	removeBtn := widget.NewButton("删除所选规则", func() {
+		if selected < 0 || selected >= len(chain.Rules) {
+			dialog.ShowError(errors.New("请先选择一条规则"), window)
+			return
+		}
+		chain.Rules = append(append([]utils.RenameRule{}, chain.Rules[:selected]...), chain.Rules[selected+1:]...)
+		changed()
+	})
+	clearBtn := widget.NewButton("清空规则", func() {
+		chain.Rules = nil
+		changed()
+	})
+
+	// 文件筛选
+	filterEntry := widget.NewEntry()
+	filterEntry.SetPlaceHolder("只处理匹配的文件，如 *.mp3，留空处理全部文件")
+	filterEntry.OnChanged = func(value string) {
+		chain.Filter = strings.TrimSpace(value)
+		onChange(chain)
+	}
+
+	// 保存和加载规则按钮
+	saveBtn := widget.NewButton("保存规则", func() {
+		fd := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			path := writer.URI().Path()
+			writer.Close()
+			if err := utils.SaveRuleChain(path, chain); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			dialog.ShowInformation("成功", "规则已保存到 "+path, window)
+		}, window)
+		fd.SetFileName("重命名规则.json")
+		fd.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+		fd.Show()
+	})
+	loadBtn := widget.NewButton("加载规则", func() {
+		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			path := reader.URI().Path()
+			reader.Close()
+			loaded, err := utils.LoadRuleChain(path)
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			chain = loaded
+			filterEntry.SetText(chain.Filter)
+			changed()
+		}, window)
+		fd.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+		fd.Show()
+	})
+
+	top := container.NewVBox(
+		container.NewGridWithColumns(2, widget.NewLabel("规则类型"), typeSelect),
+		fields,
+		addBtn,
+		widget.NewLabel("规则（按顺序依次应用）"),
+	)
+	bottom := container.NewVBox(
+		container.NewGridWithColumns(2, removeBtn, clearBtn),
+		filterEntry,
+		container.NewGridWithColumns(2, saveBtn, loadBtn),
+	)
+	return container.NewBorder(top, bottom, nil, nil, ruleList)
+}
+
+// templateHelp 文件名模板的字段说明
+const templateHelp = `可用字段：
+{name} 原文件名（不含扩展名），{ext} 扩展名
+{seq:03} 按自然排序的序号，03 表示补足 3 位
+{mtime:2006-01-02} 修改时间，格式按 Go 的时间格式书写
+{size} 文件字节数，{size:mb} 以 MB 为单位
+{hash} SHA-256 值，{hash:md5:8} 取 MD5 值的前 8 位
+{exif.DateTimeOriginal:2006-01-02} 照片拍摄时间，{exif.Model} 相机型号等 EXIF 字段
+{id3.artist} {id3.title} {id3.album} {id3.year} {id3.track:02} {id3.genre} MP3 标签
+用 | 列出候选字段，如 {exif.DateTimeOriginal|mtime}，取第一个有值的字段
+{{ 和 }} 表示大括号本身；字段值中的 / 和 \ 会被替换为 _`
+
+// orderHelp 按顺序重命名的说明
+const orderHelp = `表格中只需要一列新文件名，不需要原文件名。目标文件夹中的文件按所选方式排列后，第 1 个文件使用第 1 个新文件名，依此类推。
+
+排列方式：
+文件名顺序：按自然顺序，IMG_2 排在 IMG_10 之前
+修改时间：从早到晚
+拍摄时间（EXIF）：从早到晚，没有拍摄时间的文件使用修改时间
+
+新文件名没有写扩展名时保留原文件的扩展名。文件数与新文件名数不一致时会在预览中提示，多出的文件或新文件名不会处理。`
+
+// createOrderEditor 创建按顺序重命名的设置面板：选择只有新文件名的表格、文件的排列方式和筛选条件
+func createOrderEditor(onChange func(table string, mapping utils.ColumnMapping, order utils.PairOrder, filter string)) fyne.CanvasObject {
+	var tablePath string
+	var mapping utils.ColumnMapping
+	var order utils.PairOrder
+	tableLabel := widget.NewLabel("未选择表格")
+	tableLabel.Wrapping = fyne.TextWrapWord
+	filterEntry := widget.NewEntry()
+	filterEntry.SetPlaceHolder("只处理匹配的文件，如 *.jpg，留空处理全部文件")
+	changed := func() {
+		onChange(tablePath, mapping, order, strings.TrimSpace(filterEntry.Text))
+	}
+	filterEntry.OnChanged = func(string) { changed() }
+
+	setMapping := func(m utils.ColumnMapping) {
+		mapping = m
+		changed()
+	}
+	selectTableBtn := widget.NewButton("选择表格文件", func() {
+		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			tablePath = reader.URI().Path()
+			reader.Close()
+			selectTableMapping(tablePath, utils.MappingOrder, tableLabel, setMapping)
+		}, window)
+		fd.SetFilter(storage.NewExtensionFileFilter(utils.TableExtensions()))
+		fd.Show()
+	})
+	mappingBtn := widget.NewButton("列映射", func() {
+		if tablePath == "" {
+			dialog.ShowError(errors.New("请先选择表格文件"), window)
+			return
+		}
+		showColumnMappingDialog(tablePath, utils.MappingOrder, mapping, func(m utils.ColumnMapping) {
+			tableLabel.SetText("已选择表格文件: " + tablePath + "\n" + m.Describe(utils.MappingOrder))
+			setMapping(m)
+		})
+	})
+
+	orderOptions := make([]string, len(utils.PairOrders))
+	for i, o := range utils.PairOrders {
+		orderOptions[i] = o.String()
+	}
+	orderSelect := widget.NewSelect(orderOptions, nil)
+	orderSelect.SetSelected(utils.OrderNatural.String())
+	orderSelect.OnChanged = func(value string) {
+		if o, err := utils.ParsePairOrder(value); err == nil {
+			order = o
+			changed()
+		}
+	}
+
+	help := widget.NewLabel(orderHelp)
+	help.Wrapping = fyne.TextWrapWord
+
+	top := container.NewVBox(
+		container.NewGridWithColumns(2, selectTableBtn, mappingBtn),
+		tableLabel,
+		container.NewGridWithColumns(2, widget.NewLabel("文件排列方式"), orderSelect),
+		filterEntry,
+	)
+	return container.NewBorder(top, nil, nil, nil, container.NewVScroll(help))
+}
+
+// createTemplateEditor 创建文件名模板编辑区，模板或文件筛选条件变化时调用 onChange
+func createTemplateEditor(onChange func(template, filter string)) fyne.CanvasObject {
+	templateEntry := widget.NewEntry()
+	templateEntry.SetPlaceHolder("{exif.DateTimeOriginal|mtime:2006-01-02}_{seq:03}.{ext}")
+	filterEntry := widget.NewEntry()
+	filterEntry.SetPlaceHolder("只处理匹配的文件，如 *.jpg，留空处理全部文件")
+	changed := func(string) {
+		onChange(templateEntry.Text, strings.TrimSpace(filterEntry.Text))
+	}
+	templateEntry.OnChanged = changed
+	filterEntry.OnChanged = changed
+
+	// 示例模板
+	exampleSelect := widget.NewSelect([]string{
+		"{exif.DateTimeOriginal|mtime:2006-01-02}_{seq:03}.{ext}",
+		"{id3.artist} - {id3.title}.mp3",
+		"{id3.track:02} {id3.title}.mp3",
+		"{mtime:20060102_150405}.{ext}",
+		"{name}_{hash:md5:8}.{ext}",
+	}, func(value string) {
+		templateEntry.SetText(value)
+	})
+	exampleSelect.PlaceHolder = "选择示例模板"
+
+	help := widget.NewLabel(templateHelp)
+	help.Wrapping = fyne.TextWrapWord
+
+	top := container.NewVBox(
+		widget.NewLabel("文件名模板"),
+		templateEntry,
+		exampleSelect,
+		filterEntry,
+	)
+	return container.NewBorder(top, nil, nil, nil, container.NewVScroll(help))
+}
+
+// runHeadlessWatch 按命令行参数监视文件夹，日志输出到标准输出，收到 Ctrl+C 或终止信号时停止
+func runHeadlessWatch() int {
+	collision, err := utils.ParseCollisionPolicy(*watchCollisionFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	config := utils.WatchConfig{
+		Folder:   *watchFolderFlag,
+		Table:    *watchTableFlag,
+		Rules:    *watchRulesFlag,
+		Stable:   *watchStableFlag,
+		Existing: *watchExistingFlag,
+		Options:  utils.RenameOptions{Collision: collision, Retries: *watchRetriesFlag},
+		LogPath:  *watchLogFlag,
+	}
+	watcher, err := utils.StartFolderWatcher(config, func(line string) { fmt.Println(line) })
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	select {
+	case <-signals:
+	case <-watcher.Done():
+	}
+	watcher.Stop()
+	return 0
+}
+
+// createWatchTab 创建监视文件夹标签页：文件放入文件夹并写完后，自动按映射表格或规则改名
+func createWatchTab() fyne.CanvasObject {
+	const (
+		watchSourceTable = "映射表格"
+		watchSourceRules = "规则文件"
+	)
+	var folderPath, sourcePath string
+	var watcher *utils.FolderWatcher
+	config := utils.WatchConfig{Stable: utils.DefaultStableDuration}
+	config.Options.Retries = utils.DefaultRenameRetries
+	statusLabel := widget.NewLabel("准备就绪")
+
+	// 日志只在界面上保留最近的若干行，完整内容在日志文件中
+	const maxLogLines = 500
+	var logLines []string
+	var logMu sync.Mutex
+	logList := widget.NewList(
+		func() int {
+			logMu.Lock()
+			defer logMu.Unlock()
+			return len(logLines)
+		},
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			logMu.Lock()
+			defer logMu.Unlock()
+			if id < len(logLines) {
+				obj.(*widget.Label).SetText(logLines[id])
+			}
+		},
+	)
+	appendLog := func(line string) {
+		logMu.Lock()
+		logLines = append(logLines, line)
+		if len(logLines) > maxLogLines {
+			logLines = logLines[len(logLines)-maxLogLines:]
+		}
+		count := len(logLines)
+		logMu.Unlock()
+		logList.Refresh()
+		logList.ScrollTo(count - 1)
+	}
+
+	// 监视的文件夹
+	folderLabel := widget.NewLabel("未选择")
+	selectFolderBtn := widget.NewButton("选择监视文件夹", func() {
+		fd := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if uri == nil {
+				return
+			}
+			folderPath = uri.Path()
+			folderLabel.SetText(folderPath)
+		}, window)
+		fd.Show()
+	})
+
+	// 新文件名的来源：映射表格或保存的规则
+	sourceLabel := widget.NewLabel("未选择")
+	sourceRadio := widget.NewRadioGroup([]string{watchSourceTable, watchSourceRules}, func(value string) {
+		sourcePath = ""
+		sourceLabel.SetText("未选择")
+	})
+	sourceRadio.Horizontal = true
+	sourceRadio.SetSelected(watchSourceTable)
+	selectSourceBtn := widget.NewButton("选择文件", func() {
+		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			sourcePath = reader.URI().Path()
+			reader.Close()
+			sourceLabel.SetText(sourcePath)
 		}, window)
+		if sourceRadio.Selected == watchSourceRules {
+			fd.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+		} else {
+			fd.SetFilter(storage.NewExtensionFileFilter(utils.TableExtensions()))
+		}
 		fd.Show()
 	})
 
-	// 开始重命名按钮
-	startRenameBtn := widget.NewButton("开始重命名", func() {
-		if excelPath == "" || folderPath == "" {
-			dialog.ShowError(errors.New("请先选择Excel文件和目标文件夹"), window)
-			return
+	// 文件写完的判断时间
+	stableEntry := widget.NewEntry()
+	stableEntry.SetText(strconv.Itoa(int(utils.DefaultStableDuration / time.Second)))
+
+	// 目标冲突处理方式
+	policyOptions := make([]string, len(utils.CollisionPolicies))
+	for i, policy := range utils.CollisionPolicies {
+		policyOptions[i] = policy.String()
+	}
+	collisionSelect := widget.NewSelect(policyOptions, func(value string) {
+		if policy, err := utils.ParseCollisionPolicy(value); err == nil {
+			config.Options.Collision = policy
 		}
+	})
+	collisionSelect.SetSelected(utils.CollisionSkip.String())
 
-		go func() {
-			statusLabel.SetText("正在读取Excel文件...")
-			data, err := utils.ReadExcelForRename(excelPath)
-			if err != nil {
-				dialog.ShowError(err, window)
-				statusLabel.SetText("读取Excel文件失败")
-				return
-			}
+	existingCheck := widget.NewCheck("启动时也处理文件夹中已有的文件", func(checked bool) {
+		config.Existing = checked
+	})
 
-			statusLabel.SetText("正在重命名文件...")
-			if err := utils.RenameFiles(folderPath, data, func(current, total int, percentage float64) {
-				// 在UI线程中更新进度
-				window.Canvas().Refresh(statusLabel)
-				statusLabel.SetText(fmt.Sprintf("正在重命名语音...%.0f%%(%d/%d)", percentage, current, total))
-			}); err != nil {
-				dialog.ShowError(err, window)
-				statusLabel.SetText("重命名失败")
-				return
-			}
+	// 开始和停止监视按钮
+	var startBtn *widget.Button
+	setRunning := func(running bool) {
+		if running {
+			startBtn.SetText("停止监视")
+			selectFolderBtn.Disable()
+			selectSourceBtn.Disable()
+			sourceRadio.Disable()
+		} else {
+			startBtn.SetText("开始监视")
+			selectFolderBtn.Enable()
+			selectSourceBtn.Enable()
+			sourceRadio.Enable()
+		}
+	}
+	startBtn = widget.NewButton("开始监视", func() {
+		if watcher != nil {
+			watcher.Stop()
+			watcher = nil
+			setRunning(false)
+			statusLabel.SetText("已停止监视")
+			return
+		}
+		if folderPath == "" {
+			dialog.ShowError(errors.New("请先选择监视文件夹"), window)
+			return
+		}
+		if sourcePath == "" {
+			dialog.ShowError(errors.New("请先选择"+sourceRadio.Selected), window)
+			return
+		}
+		seconds, err := strconv.ParseFloat(strings.TrimSpace(stableEntry.Text), 64)
+		if err != nil || seconds <= 0 {
+			dialog.ShowError(errors.New("等待时间应为大于 0 的秒数"), window)
+			return
+		}
 
-			statusLabel.SetText("重命名完成")
-			dialog.ShowInformation("成功", "文件重命名完成", window)
-		}()
+		config.Folder, config.Table, config.Rules = folderPath, "", ""
+		if sourceRadio.Selected == watchSourceRules {
+			config.Rules = sourcePath
+		} else {
+			config.Table = sourcePath
+		}
+		config.Stable = time.Duration(seconds * float64(time.Second))
+		started, err := utils.StartFolderWatcher(config, appendLog)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		watcher = started
+		setRunning(true)
+		statusLabel.SetText("正在监视 " + folderPath + "，日志文件: " + watcher.LogPath())
 	})
 
-	// 布局
-	return container.NewVBox(
-		selectExcelBtn,
-		selectFolderBtn,
-		startRenameBtn,
+	form := container.NewVBox(
+		container.NewBorder(nil, nil, selectFolderBtn, nil, folderLabel),
+		container.NewHBox(widget.NewLabel("新文件名来源"), sourceRadio),
+		container.NewBorder(nil, nil, selectSourceBtn, nil, sourceLabel),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("文件大小不变多少秒后处理"), stableEntry,
+			widget.NewLabel("目标已存在时"), collisionSelect,
+		),
+		existingCheck,
+		startBtn,
 		statusLabel,
 	)
+	return container.NewBorder(form, nil, nil, nil, logList)
 }
 
 // createTTSTab 创建文字转语音标签页
 func createTTSTab() fyne.CanvasObject {
 	// 状态变量
 	var excelPath, outputPath string
+	var mapping utils.ColumnMapping
 	var config utils.TTSConfig
 	statusLabel := widget.NewLabel("准备就绪")
 
@@ -188,8 +1522,8 @@ func createTTSTab() fyne.CanvasObject {
 		Volume: "+0%",
 	}
 
-	// Excel文件选择按钮
-	selectExcelBtn := widget.NewButton("选择Excel文件", func() {
+	// 表格文件选择按钮（xlsx、csv、tsv、ods、json、yaml）
+	selectExcelBtn := widget.NewButton("选择表格文件", func() {
 		fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
 			if err != nil {
 				dialog.ShowError(err, window)
@@ -199,12 +1533,24 @@ func createTTSTab() fyne.CanvasObject {
 				return
 			}
 			excelPath = reader.URI().Path()
-			statusLabel.SetText("已选择Excel文件: " + excelPath)
+			selectTableMapping(excelPath, utils.MappingTTS, statusLabel, func(m utils.ColumnMapping) { mapping = m })
 		}, window)
-		fd.SetFilter(storage.NewExtensionFileFilter([]string{".xlsx"}))
+		fd.SetFilter(storage.NewExtensionFileFilter(utils.TableExtensions()))
 		fd.Show()
 	})
 
+	// 列映射按钮：选择工作表以及文本所在的列
+	mappingBtn := widget.NewButton("列映射", func() {
+		if excelPath == "" {
+			dialog.ShowError(errors.New("请先选择表格文件"), window)
+			return
+		}
+		showColumnMappingDialog(excelPath, utils.MappingTTS, mapping, func(m utils.ColumnMapping) {
+			mapping = m
+			statusLabel.SetText("已选择表格文件: " + excelPath + "\n" + mapping.Describe(utils.MappingTTS))
+		})
+	})
+
 	// 输出文件夹选择按钮
 	selectOutputFolderBtn := widget.NewButton("选择输出文件夹", func() {
 		fd := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
@@ -272,27 +1618,38 @@ func createTTSTab() fyne.CanvasObject {
 	}
 	volumeSlider.SetValue(0)
 
+	// 生成文件名时的文字转换，如汉字转拼音
+	const noScriptConversion = "不转换"
+	scriptOptions := []string{noScriptConversion}
+	for _, conversion := range utils.ScriptConversions {
+		scriptOptions = append(scriptOptions, conversion.String())
+	}
+	nameScriptSelect := widget.NewSelect(scriptOptions, func(value string) {
+		config.NameScript = ""
+		if conversion, err := utils.ParseScriptConversion(value); err == nil {
+			config.NameScript = conversion
+		}
+	})
+	nameScriptSelect.SetSelected(noScriptConversion)
+
 	// 开始转换按钮
 	startConvertBtn := widget.NewButton("开始转换", func() {
 		if excelPath == "" || outputPath == "" {
-			dialog.ShowError(errors.New("请先选择Excel文件和输出文件夹"), window)
+			dialog.ShowError(errors.New("请先选择表格文件和输出文件夹"), window)
 			return
 		}
 
 		go func() {
-			statusLabel.SetText("正在读取Excel文件...")
-			texts, err := utils.ReadExcelForTTS(excelPath)
-			if err != nil {
-				dialog.ShowError(err, window)
-				statusLabel.SetText("读取Excel文件失败")
-				return
-			}
-
-			statusLabel.SetText("正在转换语音...0%")
-			if err := utils.BatchTextToSpeech(texts, outputPath, config, func(current, total int, percentage float64) {
+			// 边读取表格边转换，读到一行文本就立即生成语音
+			statusLabel.SetText("正在读取表格文件...")
+			if err := utils.TableTextToSpeech(excelPath, mapping, outputPath, config, func(current, total int, percentage float64) {
 				// 在UI线程中更新进度
 				window.Canvas().Refresh(statusLabel)
-				statusLabel.SetText(fmt.Sprintf("正在转换语音...%.0f%%(%d/%d)", percentage, current, total))
+				if total == 0 {
+					statusLabel.SetText(fmt.Sprintf("正在转换语音...第 %d 行", current))
+					return
+				}
+				statusLabel.SetText(fmt.Sprintf("正在转换语音...%.0f%%(%d/%d 行)", percentage, current, total))
 			}); err != nil {
 				dialog.ShowError(err, window)
 				statusLabel.SetText(fmt.Sprintf("转换失败: %v", err))
@@ -306,7 +1663,7 @@ func createTTSTab() fyne.CanvasObject {
 
 	// 布局
 	return container.NewVBox(
-		selectExcelBtn,
+		container.NewBorder(nil, nil, nil, mappingBtn, selectExcelBtn),
 		selectOutputFolderBtn,
 		container.NewGridWithColumns(2,
 			widget.NewLabel("语音选择"),
@@ -315,8 +1672,613 @@ func createTTSTab() fyne.CanvasObject {
 			container.NewBorder(nil, nil, nil, rateLabel, rateSlider),
 			widget.NewLabel("音量调节"),
 			container.NewBorder(nil, nil, nil, volumeLabel, volumeSlider),
+			widget.NewLabel("文件名文字转换"),
+			nameScriptSelect,
 		),
 		startConvertBtn,
 		statusLabel,
 	)
-}
\ No newline at end of file
+}
+
+// readProgress 读取表格文件时在状态栏显示已读取的行数
+func readProgress(statusLabel *widget.Label) utils.ProgressCallback {
+	return func(current, total int, percentage float64) {
+		window.Canvas().Refresh(statusLabel)
+		if total == 0 {
+			statusLabel.SetText(fmt.Sprintf("正在读取表格文件...已读取 %d 行", current))
+			return
+		}
+		statusLabel.SetText(fmt.Sprintf("正在读取表格文件...%.0f%%(%d/%d 行)", percentage, current, total))
+	}
+}
+
+// runRename 执行已确认的重命名计划，完成后显示每一行的结果
+func runRename(plan *utils.RenamePlan, excelPath string, statusLabel *widget.Label) {
+	statusLabel.SetText("正在重命名文件...")
+	plan.CopyProgress = func(name string, copied, total int64) {
+		// 目标在其他磁盘上时按字节显示复制进度
+		percentage := 100.0
+		if total > 0 {
+			percentage = float64(copied) / float64(total) * 100
+		}
+		window.Canvas().Refresh(statusLabel)
+		statusLabel.SetText(fmt.Sprintf("正在跨设备复制 %s...%.0f%%(%.1f/%.1f MB)", name, percentage, float64(copied)/(1<<20), float64(total)/(1<<20)))
+	}
+	plan.BackupProgress = func(current, total int, percentage float64) {
+		window.Canvas().Refresh(statusLabel)
+		statusLabel.SetText(fmt.Sprintf("正在备份受影响的文件...%.0f%%(%d/%d)", percentage, current, total))
+	}
+	results, err := utils.ExecuteRenamePlan(plan, func(current, total int, percentage float64) {
+		// 在UI线程中更新进度
+		window.Canvas().Refresh(statusLabel)
+		statusLabel.SetText(fmt.Sprintf("正在重命名文件...%.0f%%(%d/%d)", percentage, current, total))
+	})
+	if err != nil {
+		dialog.ShowError(err, window)
+	}
+
+	summary := renameResultSummary(results)
+	if plan.BackupPath != "" {
+		summary += "，备份: " + plan.BackupPath
+	}
+	statusLabel.SetText("重命名完成: " + summary)
+
+	// 有可以重试的失败行时，允许只重新执行这些行
+	var onRetry func()
+	if len(utils.RetryData(plan, results)) > 0 {
+		onRetry = func() { retryFailedRows(plan, results, excelPath, statusLabel) }
+	}
+	showRenameResultsDialog(results, excelPath, summary, onRetry)
+}
+
+// retryFailedRows 为上次执行中失败的行重新生成计划，确认后只执行这些行
+func retryFailedRows(plan *utils.RenamePlan, results []utils.RenameResult, excelPath string, statusLabel *widget.Label) {
+	go func() {
+		statusLabel.SetText("正在为失败的行生成重命名计划...")
+		retryPlan, err := utils.PlanRetry(plan, results)
+		if err != nil {
+			dialog.ShowError(err, window)
+			statusLabel.SetText("生成重命名计划失败")
+			return
+		}
+		statusLabel.SetText("请确认重试计划: " + retryPlan.Summary())
+		showRenamePlanDialog(retryPlan, func() {
+			go runRename(retryPlan, excelPath, statusLabel)
+		})
+	}()
+}
+
+// retryCountLabel 返回重试次数在界面上的名称
+func retryCountLabel(count int) string {
+	if count == 0 {
+		return "不重试"
+	}
+	return fmt.Sprintf("等待后重试，最多 %d 次", count)
+}
+
+// renameResultSummary 返回重命名结果的统计摘要
+func renameResultSummary(results []utils.RenameResult) string {
+	summary := fmt.Sprintf("成功 %d 行，跳过 %d 行，失败 %d 行",
+		utils.CountRenameResults(results, utils.RenameSucceeded),
+		utils.CountRenameResults(results, utils.RenameSkipped),
+		utils.CountRenameResults(results, utils.RenameFailed))
+	if aborted := utils.CountRenameResults(results, utils.RenameAborted); aborted > 0 {
+		summary += fmt.Sprintf("，中止 %d 行", aborted)
+	}
+	return summary
+}
+
+// showRenameResultsDialog 以表格形式显示每一行的重命名结果，可写回源表格或另存为报告；
+// onRetry 不为 nil 时可重试失败的行
+func showRenameResultsDialog(results []utils.RenameResult, excelPath, summary string, onRetry func()) {
+	table := newStringTable(
+		[]string{"行号", "操作", "原路径", "新路径", "结果", "错误类型", "说明"},
+		[]float32{60, 80, 220, 220, 60, 100, 260},
+		func() int { return len(results) },
+		func(row, col int) string {
+			result := results[row]
+			switch col {
+			case 0:
+				return fmt.Sprint(result.Row)
+			case 1:
+				return result.Op.String()
+			case 2:
+				return result.OldPath
+			case 3:
+				return result.NewPath
+			case 4:
+				return result.Status.String()
+			case 5:
+				return result.Kind.String()
+			default:
+				return result.Detail
+			}
+		},
+	)
+
+	// 写回源表格按钮
+	writeBackBtn := widget.NewButton("写入源表格的“结果”工作表", func() {
+		if err := utils.WriteRenameResultSheet(excelPath, results); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		dialog.ShowInformation("成功", "结果已写入 "+excelPath, window)
+	})
+	if excelPath == "" || !utils.IsWorkbook(excelPath) {
+		writeBackBtn.Disable()
+	}
+
+	// 另存为报告按钮
+	exportBtn := widget.NewButton("另存为结果报告", func() {
+		fd := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			reportPath := writer.URI().Path()
+			writer.Close()
+			if err := utils.ExportRenameReport(reportPath, results); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			dialog.ShowInformation("成功", "结果报告已保存到 "+reportPath, window)
+		}, window)
+		fd.SetFileName("重命名结果.xlsx")
+		fd.SetFilter(storage.NewExtensionFileFilter([]string{".xlsx"}))
+		fd.Show()
+	})
+
+	// 重试失败的行按钮：只重新执行上次失败的行
+	var d dialog.Dialog
+	retryBtn := widget.NewButton("重试失败的行", func() {
+		d.Hide()
+		onRetry()
+	})
+	if onRetry == nil {
+		retryBtn.Disable()
+	}
+
+	summaryLabel := widget.NewLabel(summary)
+	buttons := container.NewGridWithColumns(3, writeBackBtn, exportBtn, retryBtn)
+	d = dialog.NewCustom("重命名结果", "关闭", container.NewBorder(summaryLabel, buttons, nil, nil, table), window)
+	d.Resize(fyne.NewSize(950, 600))
+	d.Show()
+}
+
+// showMultiFolderDialog 选择要应用同一份表格的多个文件夹，可直接添加文件夹，也可按通配符添加父文件夹中的子文件夹
+func showMultiFolderDialog(excelPath string, mapping utils.ColumnMapping, options utils.RenameOptions, statusLabel *widget.Label) {
+	var folders []string
+	selected := -1
+	folderList := widget.NewList(
+		func() int { return len(folders) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(folders[id])
+		},
+	)
+	folderList.OnSelected = func(id widget.ListItemID) { selected = id }
+	addFolder := func(folder string) {
+		for _, existing := range folders {
+			if existing == folder {
+				return
+			}
+		}
+		folders = append(folders, folder)
+		folderList.Refresh()
+	}
+
+	// 添加单个文件夹
+	addBtn := widget.NewButton("添加文件夹", func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if uri != nil {
+				addFolder(uri.Path())
+			}
+		}, window)
+	})
+
+	// 按通配符添加父文件夹中的子文件夹，如 release 中的 *
+	patternEntry := widget.NewEntry()
+	patternEntry.SetText("*")
+	addPatternBtn := widget.NewButton("选择父文件夹，按通配符添加子文件夹", func() {
+		pattern := strings.TrimSpace(patternEntry.Text)
+		if pattern == "" {
+			dialog.ShowError(errors.New("请输入子文件夹的通配符，如 *"), window)
+			return
+		}
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if uri == nil {
+				return
+			}
+			matched, err := utils.ExpandRenameFolders([]string{filepath.Join(uri.Path(), pattern)})
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			for _, folder := range matched {
+				addFolder(folder)
+			}
+		}, window)
+	})
+
+	removeBtn := widget.NewButton("删除所选文件夹", func() {
+		if selected < 0 || selected >= len(folders) {
+			return
+		}
+		folders = append(folders[:selected], folders[selected+1:]...)
+		selected = -1
+		folderList.UnselectAll()
+		folderList.Refresh()
+	})
+
+	hint := widget.NewLabel("同一份表格将依次应用到以下每个文件夹。某个文件夹中有行无法执行时不修改该文件夹，执行中有行失败时撤销该文件夹已完成的修改，不影响其他文件夹")
+	hint.Wrapping = fyne.TextWrapWord
+	buttons := container.NewVBox(
+		container.NewGridWithColumns(2, addBtn, removeBtn),
+		container.NewBorder(nil, nil, widget.NewLabel("子文件夹通配符"), addPatternBtn, patternEntry),
+	)
+	content := container.NewBorder(hint, buttons, nil, nil, folderList)
+
+	d := dialog.NewCustomConfirm("应用到多个文件夹", "预览", "取消", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		if len(folders) == 0 {
+			dialog.ShowError(errors.New("请至少添加一个文件夹"), window)
+			return
+		}
+
+		go func() {
+			statusLabel.SetText("正在读取表格文件...")
+			data, err := utils.ReadRenameTable(excelPath, mapping, readProgress(statusLabel))
+			if err != nil {
+				dialog.ShowError(err, window)
+				statusLabel.SetText("读取表格文件失败")
+				return
+			}
+
+			statusLabel.SetText("正在生成重命名计划...")
+			folderResults, err := utils.RenameFilesInFolders(folders, data, options, func(plans []*utils.RenamePlan) bool {
+				// 等待用户在预览中确认后再执行
+				statusLabel.SetText(fmt.Sprintf("请确认 %d 个文件夹的重命名计划", len(plans)))
+				confirmed := make(chan bool, 1)
+				showFolderPlansDialog(plans, func(ok bool) { confirmed <- ok })
+				if !<-confirmed {
+					return false
+				}
+				statusLabel.SetText("正在重命名文件...")
+				return true
+			}, func(current, total int, percentage float64) {
+				window.Canvas().Refresh(statusLabel)
+				statusLabel.SetText(fmt.Sprintf("正在重命名文件...%.0f%%(%d/%d 个文件夹)", percentage, current, total))
+			})
+			if err != nil {
+				dialog.ShowError(err, window)
+				statusLabel.SetText("生成重命名计划失败")
+				return
+			}
+			if folderResults == nil {
+				statusLabel.SetText("已取消多文件夹重命名")
+				return
+			}
+			showFolderRenameSummary(folderResults, excelPath, statusLabel)
+		}()
+	}, window)
+	d.Resize(fyne.NewSize(700, 450))
+	d.Show()
+}
+
+// showFolderPlansDialog 列出每个文件夹的重命名计划摘要，可查看所选文件夹的详细计划，关闭时把是否确认交给 onDone
+func showFolderPlansDialog(plans []*utils.RenamePlan, onDone func(ok bool)) {
+	table := newStringTable(
+		[]string{"文件夹", "计划"},
+		[]float32{300, 560},
+		func() int { return len(plans) },
+		func(row, col int) string {
+			if col == 0 {
+				return plans[row].FolderPath
+			}
+			return plans[row].Summary()
+		},
+	)
+	table.OnSelected = func(id widget.TableCellID) {
+		table.UnselectAll()
+		showRenamePlanDialog(plans[id.Row], nil)
+	}
+
+	summary := widget.NewLabel("点击某一行查看该文件夹的详细计划。有行无法执行的文件夹不会被修改")
+	summary.Wrapping = fyne.TextWrapWord
+	d := dialog.NewCustomConfirm("多文件夹重命名预览", "确认重命名", "取消", container.NewBorder(summary, nil, nil, nil, table), onDone, window)
+	d.Resize(fyne.NewSize(950, 500))
+	d.Show()
+}
+
+// showFolderRenameSummary 在状态栏显示多文件夹重命名的统计，并按文件夹显示结果
+func showFolderRenameSummary(folderResults []utils.FolderRenameResult, excelPath string, statusLabel *widget.Label) {
+	failed := 0
+	for _, result := range folderResults {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	summary := fmt.Sprintf("共 %d 个文件夹，%d 个全部成功，%d 个未修改或已回滚", len(folderResults), len(folderResults)-failed, failed)
+	statusLabel.SetText("重命名完成: " + summary)
+	showFolderResultsDialog(folderResults, excelPath, summary)
+}
+
+// showFolderResultsDialog 按文件夹显示多文件夹重命名的结果，点击某一行查看该文件夹每一行的结果
+func showFolderResultsDialog(folderResults []utils.FolderRenameResult, excelPath, summary string) {
+	table := newStringTable(
+		[]string{"文件夹", "结果", "成功行数", "说明"},
+		[]float32{300, 80, 80, 400},
+		func() int { return len(folderResults) },
+		func(row, col int) string {
+			result := folderResults[row]
+			switch col {
+			case 0:
+				return result.Folder
+			case 1:
+				switch {
+				case result.Err == nil:
+					return "成功"
+				case result.RolledBack:
+					return "已回滚"
+				}
+				return "未修改"
+			case 2:
+				return fmt.Sprint(result.Succeeded)
+			default:
+				return result.Summary()
+			}
+		},
+	)
+	table.OnSelected = func(id widget.TableCellID) {
+		table.UnselectAll()
+		result := folderResults[id.Row]
+		showRenameResultsDialog(result.Results, excelPath, result.Folder+": "+renameResultSummary(result.Results), nil)
+	}
+
+	summaryLabel := widget.NewLabel(summary + "\n点击某一行查看该文件夹每一行的结果")
+	summaryLabel.Wrapping = fyne.TextWrapWord
+	d := dialog.NewCustom("多文件夹重命名结果", "关闭", container.NewBorder(summaryLabel, nil, nil, nil, table), window)
+	d.Resize(fyne.NewSize(950, 500))
+	d.Show()
+}
+
+// confirmUndoRename 确认后撤销指定的一次重命名
+func confirmUndoRename(journal *utils.RenameJournal, statusLabel *widget.Label) {
+	message := fmt.Sprintf("确定撤销 %s 在以下文件夹中的 %d 个文件重命名吗？\n%s",
+		journal.Time.Format("2006-01-02 15:04:05"), journal.SuccessCount(), journal.Folder)
+	dialog.ShowConfirm("撤销重命名", message, func(ok bool) {
+		if !ok {
+			return
+		}
+		go func() {
+			statusLabel.SetText("正在撤销重命名...")
+			if err := utils.UndoRename(journal, func(current, total int, percentage float64) {
+				window.Canvas().Refresh(statusLabel)
+				statusLabel.SetText(fmt.Sprintf("正在撤销重命名...%.0f%%(%d/%d)", percentage, current, total))
+			}); err != nil {
+				dialog.ShowError(err, window)
+				statusLabel.SetText("撤销失败")
+				return
+			}
+
+			statusLabel.SetText("撤销完成")
+			dialog.ShowInformation("成功", "已恢复原文件名", window)
+		}()
+	}, window)
+}
+
+// confirmRestoreBackup 确认后按备份压缩包恢复执行前的文件
+func confirmRestoreBackup(archivePath string, statusLabel *widget.Label) {
+	manifest, err := utils.ReadBackupManifest(archivePath)
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	message := fmt.Sprintf("确定按 %s 的备份（%s）恢复以下文件夹中的 %d 个文件吗？\n%s\n仍在原位置且未改变的文件会被跳过，原位置已被其他文件占用时不会覆盖。",
+		manifest.Time.Format("2006-01-02 15:04:05"), manifest.Mode, len(manifest.Entries), manifest.Folder)
+	// 有对应的重命名记录时同时删除该次复制出的文件和新建的文件夹，否则只能恢复备份中的文件
+	journal := utils.FindBackupJournal(archivePath)
+	if journal != nil {
+		message += "\n该次复制出的文件和新建的空文件夹会被删除。"
+	} else {
+		message += "\n没有找到对应的重命名记录，该次复制出的文件和新建的文件夹不会被删除，需要手动处理。"
+	}
+	dialog.ShowConfirm("从备份恢复", message, func(ok bool) {
+		if !ok {
+			return
+		}
+		go func() {
+			statusLabel.SetText("正在从备份恢复...")
+			progress := func(current, total int, percentage float64) {
+				window.Canvas().Refresh(statusLabel)
+				statusLabel.SetText(fmt.Sprintf("正在从备份恢复...%.0f%%(%d/%d)", percentage, current, total))
+			}
+			var err error
+			if journal != nil {
+				err = utils.RestoreJournalBackup(journal, progress)
+			} else {
+				err = utils.RestoreRenameBackup(archivePath, progress)
+			}
+			if err != nil {
+				dialog.ShowError(err, window)
+				statusLabel.SetText("恢复失败")
+				return
+			}
+
+			statusLabel.SetText("恢复完成")
+			dialog.ShowInformation("成功", "已按备份恢复执行前的文件", window)
+		}()
+	}, window)
+}
+
+// showRenameHistoryDialog 显示重命名历史，可回滚任意一次尚未撤销的重命名
+func showRenameHistoryDialog(statusLabel *widget.Label) {
+	journals, err := utils.ListRenameJournals()
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	if len(journals) == 0 {
+		dialog.ShowInformation("重命名历史", "暂无重命名记录", window)
+		return
+	}
+
+	selected := -1
+	table := newStringTable(
+		[]string{"时间", "文件夹", "成功/总数", "备份", "状态"},
+		[]float32{170, 420, 90, 60, 80},
+		func() int { return len(journals) },
+		func(row, col int) string {
+			journal := journals[row]
+			switch col {
+			case 0:
+				return journal.Time.Format("2006-01-02 15:04:05")
+			case 1:
+				return journal.Folder
+			case 2:
+				return fmt.Sprintf("%d/%d", journal.SuccessCount(), len(journal.Entries))
+			case 3:
+				if journal.Backup != "" {
+					return "有"
+				}
+				return ""
+			default:
+				if journal.Undone() {
+					return "已撤销"
+				}
+				return "可回滚"
+			}
+		},
+	)
+	table.OnSelected = func(id widget.TableCellID) {
+		selected = id.Row
+	}
+
+	var d dialog.Dialog
+	rollbackBtn := widget.NewButton("回滚所选记录", func() {
+		if selected < 0 {
+			dialog.ShowError(errors.New("请先选择一条记录"), window)
+			return
+		}
+		journal := journals[selected]
+		if problems := utils.CheckUndo(journal); len(problems) > 0 {
+			dialog.ShowError(fmt.Errorf("无法回滚:\n%s", strings.Join(problems, "\n")), window)
+			return
+		}
+		d.Hide()
+		confirmUndoRename(journal, statusLabel)
+	})
+	restoreBtn := widget.NewButton("从所选记录的备份恢复", func() {
+		if selected < 0 {
+			dialog.ShowError(errors.New("请先选择一条记录"), window)
+			return
+		}
+		if journals[selected].Backup == "" {
+			dialog.ShowError(errors.New("该次重命名没有备份"), window)
+			return
+		}
+		d.Hide()
+		confirmRestoreBackup(journals[selected].Backup, statusLabel)
+	})
+
+	buttons := container.NewGridWithColumns(2, rollbackBtn, restoreBtn)
+	d = dialog.NewCustom("重命名历史", "关闭", container.NewBorder(nil, buttons, nil, nil, table), window)
+	d.Resize(fyne.NewSize(850, 500))
+	d.Show()
+}
+
+// showRenamePlanDialog 以表格形式显示重命名计划，确认后才执行；onConfirm 为 nil 时只查看不执行
+func showRenamePlanDialog(plan *utils.RenamePlan, onConfirm func()) {
+	table := newStringTable(
+		[]string{"行号", "操作", "原文件名", "新文件名", "状态", "说明", "文件名修正", "匹配说明"},
+		[]float32{60, 80, 200, 200, 100, 260, 200, 200},
+		func() int { return len(plan.Items) },
+		func(row, col int) string {
+			item := plan.Items[row]
+			switch col {
+			case 0:
+				return fmt.Sprint(item.Row)
+			case 1:
+				return item.Op.String()
+			case 2:
+				return item.OldName
+			case 3:
+				return item.NewName
+			case 4:
+				return item.Status.String()
+			case 5:
+				return item.Detail
+			case 6:
+				return item.NameFix
+			default:
+				return item.Match
+			}
+		},
+	)
+
+	summary := widget.NewLabel(plan.Summary())
+	summary.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(summary, nil, nil, nil, table)
+
+	aborted := plan.Options.Collision == utils.CollisionFail && plan.ConflictCount() > 0
+	if aborted {
+		summary.SetText(plan.Summary() + "\n存在文件名冲突，按当前设置将中止整批重命名，请修改后重试")
+	}
+	if plan.RunnableCount() == 0 || aborted || onConfirm == nil {
+		d := dialog.NewCustom("重命名预览", "关闭", content, window)
+		d.Resize(fyne.NewSize(1000, 600))
+		d.Show()
+		return
+	}
+
+	d := dialog.NewCustomConfirm("重命名预览", "确认重命名", "取消", content, func(ok bool) {
+		if ok {
+			onConfirm()
+		}
+	}, window)
+	d.Resize(fyne.NewSize(1000, 600))
+	d.Show()
+}
+
+// newStringTable 创建带表头的只读文本表格
+func newStringTable(headers []string, widths []float32, rows func() int, cell func(row, col int) string) *widget.Table {
+	table := widget.NewTableWithHeaders(
+		func() (int, int) { return rows(), len(headers) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(cell(id.Row, id.Col))
+		},
+	)
+	table.ShowHeaderColumn = false
+	table.CreateHeader = func() fyne.CanvasObject {
+		label := widget.NewLabel("")
+		label.TextStyle = fyne.TextStyle{Bold: true}
+		return label
+	}
+	table.UpdateHeader = func(id widget.TableCellID, o fyne.CanvasObject) {
+		if id.Col >= 0 && id.Col < len(headers) {
+			o.(*widget.Label).SetText(headers[id.Col])
+		}
+	}
+	for col, width := range widths {
+		table.SetColumnWidth(col, width)
+	}
+	return table
+}
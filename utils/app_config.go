@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// appConfigDir 返回本程序在用户配置目录下的存放目录
+func appConfigDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取配置目录失败: %v", err)
+	}
+	return filepath.Join(configDir, "general_purpose_program"), nil
+}
+
+// writeConfigFile 先写临时文件再替换，避免写到一半时留下损坏的配置
+func writeConfigFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建配置目录失败: %v", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入 %s 失败: %v", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,404 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 列映射的用途，同一个工作簿可以分别为重命名、按顺序重命名和文字转语音记住不同的映射
+const (
+	MappingRename = "rename"
+	MappingOrder  = "order" // 按顺序重命名：表格中只有新文件名，按排列顺序与文件配对
+	MappingTTS    = "tts"
+)
+
+// ColumnMapping 表格的工作表和列映射，列号从 0 开始，-1 表示不使用
+type ColumnMapping struct {
+	Sheet     string `json:"sheet,omitempty"` // 工作表名称，为空时使用第一个工作表
+	HeaderRow int    `json:"header_row"`      // 标题行的行号（从 1 开始），0 表示没有标题行
+	OldName   int    `json:"old_name"`        // 重命名：原文件名所在的列
+	NewName   int    `json:"new_name"`        // 重命名：新文件名所在的列
+	Operation int    `json:"operation"`       // 重命名：操作所在的列，-1 表示全部为重命名
+	Text      int    `json:"text"`            // 文字转语音：文本所在的列
+
+	// 重命名：新文件名模板，如 {B}_{C:03}_{D}.{ext}，不为空时由多列组合出新文件名，不再使用新文件名所在的列
+	NewNameTemplate string `json:"new_name_template,omitempty"`
+}
+
+// UnmarshalJSON 读取记住的映射，缺少的字段使用默认值，兼容没有操作列的旧设置
+func (m *ColumnMapping) UnmarshalJSON(data []byte) error {
+	type plain ColumnMapping
+	mapping := plain(DefaultColumnMapping())
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return err
+	}
+	*m = ColumnMapping(mapping)
+	return nil
+}
+
+// MappingSource 列映射的来源
+type MappingSource int
+
+const (
+	MappingDefault    MappingSource = iota // 未识别出标题，使用默认的列
+	MappingDetected                        // 根据标题行自动识别
+	MappingRemembered                      // 用户之前为该工作簿保存的设置
+)
+
+// DefaultColumnMapping 默认映射：第一个工作表，第 1 行为标题，重命名使用 A、B 列且没有操作列，文字转语音使用 A 列
+func DefaultColumnMapping() ColumnMapping {
+	return ColumnMapping{HeaderRow: 1, OldName: 0, NewName: 1, Operation: -1, Text: 0}
+}
+
+// columnAliases 自动识别标题时使用的列名，比较前会转为小写并去掉空格、下划线和连字符
+var columnAliases = map[string][]string{
+	"old":  {"原文件名", "旧文件名", "原名称", "旧名称", "原名", "旧名", "oldname", "old", "source", "from"},
+	"new":  {"新文件名", "新名称", "新名", "目标文件名", "newname", "new", "target", "to"},
+	"text": {"文本", "文字", "内容", "朗读内容", "text", "content"},
+	"op":   {"操作", "操作类型", "动作", "operation", "op", "action"},
+}
+
+// matchColumnAlias 判断标题是否为指定字段的列名
+func matchColumnAlias(header, field string) bool {
+	header = strings.ToLower(strings.TrimSpace(header))
+	header = strings.NewReplacer(" ", "", "_", "", "-", "").Replace(header)
+	for _, alias := range columnAliases[field] {
+		if header == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// detectHeaderRows 自动识别时在前几行中查找标题行
+const detectHeaderRows = 10
+
+// DetectColumnMapping 在前 10 行中查找标题行并识别各列，识别失败时返回默认映射和 false，
+// 按顺序重命名的默认映射使用 A 列作为新文件名
+func DetectColumnMapping(rows [][]string, purpose string) (ColumnMapping, bool) {
+	mapping := DefaultColumnMapping()
+	if purpose == MappingOrder {
+		mapping.NewName = 0
+	}
+	for i := 0; i < len(rows) && i < detectHeaderRows; i++ {
+		oldCol, newCol, textCol, opCol := -1, -1, -1, -1
+		for col, header := range rows[i] {
+			switch {
+			case oldCol < 0 && matchColumnAlias(header, "old"):
+				oldCol = col
+			case newCol < 0 && matchColumnAlias(header, "new"):
+				newCol = col
+			case textCol < 0 && matchColumnAlias(header, "text"):
+				textCol = col
+			case opCol < 0 && matchColumnAlias(header, "op"):
+				opCol = col
+			}
+		}
+		if purpose == MappingRename && oldCol >= 0 && newCol >= 0 {
+			mapping.HeaderRow, mapping.OldName, mapping.NewName, mapping.Operation = i+1, oldCol, newCol, opCol
+			return mapping, true
+		}
+		if purpose == MappingOrder && newCol >= 0 {
+			mapping.HeaderRow, mapping.NewName = i+1, newCol
+			return mapping, true
+		}
+		if purpose == MappingTTS && textCol >= 0 {
+			mapping.HeaderRow, mapping.Text = i+1, textCol
+			return mapping, true
+		}
+	}
+	return mapping, false
+}
+
+// ColumnName 返回列号对应的字母列名，如 0 对应 A
+func ColumnName(col int) string {
+	name, err := excelize.ColumnNumberToName(col + 1)
+	if err != nil {
+		return fmt.Sprint(col + 1)
+	}
+	return name
+}
+
+// Describe 返回映射的中文说明
+func (m ColumnMapping) Describe(purpose string) string {
+	var parts []string
+	if m.Sheet != "" {
+		parts = append(parts, "工作表 "+m.Sheet)
+	}
+	if m.HeaderRow > 0 {
+		parts = append(parts, fmt.Sprintf("第 %d 行为标题", m.HeaderRow))
+	} else {
+		parts = append(parts, "无标题行")
+	}
+	if purpose == MappingTTS {
+		parts = append(parts, "文本: "+ColumnName(m.Text)+" 列")
+	} else if purpose == MappingOrder {
+		parts = append(parts, "新文件名: "+ColumnName(m.NewName)+" 列")
+	} else {
+		parts = append(parts, "原文件名: "+ColumnName(m.OldName)+" 列")
+		if m.NewNameTemplate != "" {
+			parts = append(parts, "新文件名: 按 "+m.NewNameTemplate+" 组合")
+		} else {
+			parts = append(parts, "新文件名: "+ColumnName(m.NewName)+" 列")
+		}
+		if m.Operation >= 0 {
+			parts = append(parts, "操作: "+ColumnName(m.Operation)+" 列")
+		}
+	}
+	return strings.Join(parts, "，")
+}
+
+// Validate 检查映射是否可用于指定用途
+func (m ColumnMapping) Validate(purpose string) error {
+	if m.HeaderRow < 0 {
+		return fmt.Errorf("标题行的行号不能为负数")
+	}
+	if purpose == MappingTTS {
+		if m.Text < 0 {
+			return fmt.Errorf("请指定文本所在的列")
+		}
+		return nil
+	}
+	if purpose == MappingOrder {
+		if m.NewName < 0 {
+			return fmt.Errorf("请指定新文件名所在的列")
+		}
+		return nil
+	}
+	if m.NewNameTemplate != "" {
+		if m.OldName < 0 {
+			return fmt.Errorf("请指定原文件名所在的列")
+		}
+		if _, err := ParseNewNameTemplate(m.NewNameTemplate); err != nil {
+			return err
+		}
+		if m.Operation >= 0 && m.Operation == m.OldName {
+			return fmt.Errorf("操作列不能与文件名使用同一列")
+		}
+		return nil
+	}
+	if m.OldName < 0 || m.NewName < 0 {
+		return fmt.Errorf("请指定原文件名和新文件名所在的列")
+	}
+	if m.OldName == m.NewName {
+		return fmt.Errorf("原文件名和新文件名不能使用同一列")
+	}
+	if m.Operation >= 0 && (m.Operation == m.OldName || m.Operation == m.NewName) {
+		return fmt.Errorf("操作列不能与文件名使用同一列")
+	}
+	return nil
+}
+
+// tableCellAt 取出一行中的指定单元格，超出该行长度时为空
+func tableCellAt(row []string, col int) string {
+	if col < 0 || col >= len(row) {
+		return ""
+	}
+	return row[col]
+}
+
+// ReadRenameTable 按列映射逐行读取重命名表格，只保留新旧文件名和操作，两列文件名都为空的行会被忽略。
+// 设置了新文件名模板时由多列组合出新文件名，原文件名和模板用到的列都为空的行会被忽略，组合失败的行只在预览中报告
+func ReadRenameTable(path string, mapping ColumnMapping, progressCallback ...ProgressCallback) ([]ExcelData, error) {
+	var data []ExcelData
+	err := StreamRenameTable(path, mapping, func(item ExcelData) error {
+		data = append(data, item)
+		return nil
+	}, progressCallback...)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// StreamRenameTable 与 ReadRenameTable 相同，但每读到一行就交给 fn 处理，不收集成完整的列表
+func StreamRenameTable(path string, mapping ColumnMapping, fn func(ExcelData) error, progressCallback ...ProgressCallback) error {
+	if err := mapping.Validate(MappingRename); err != nil {
+		return err
+	}
+	var composer *NewNameComposer
+	if mapping.NewNameTemplate != "" {
+		composer, _ = ParseNewNameTemplate(mapping.NewNameTemplate)
+	}
+	return StreamTableSheet(path, mapping.Sheet, func(index int, row []string) error {
+		if index <= mapping.HeaderRow {
+			return nil
+		}
+		oldName := tableCellAt(row, mapping.OldName)
+		item := ExcelData{Row: index, OldName: oldName, Operation: tableCellAt(row, mapping.Operation)}
+		if composer != nil {
+			if strings.TrimSpace(oldName) == "" && composer.blank(row) {
+				return nil
+			}
+			newName, err := composer.Compose(row, strings.TrimSpace(oldName))
+			if err != nil {
+				item.Problem = err.Error()
+			}
+			item.NewName = newName
+		} else {
+			item.NewName = tableCellAt(row, mapping.NewName)
+			if strings.TrimSpace(oldName) == "" && strings.TrimSpace(item.NewName) == "" {
+				return nil
+			}
+		}
+		return fn(item)
+	}, progressCallback...)
+}
+
+// ReadOrderTable 按列映射读取按顺序重命名的表格，只取新文件名，空白单元格会被忽略
+func ReadOrderTable(path string, mapping ColumnMapping, progressCallback ...ProgressCallback) ([]ExcelData, error) {
+	if err := mapping.Validate(MappingOrder); err != nil {
+		return nil, err
+	}
+	var data []ExcelData
+	err := StreamTableSheet(path, mapping.Sheet, func(index int, row []string) error {
+		if index <= mapping.HeaderRow {
+			return nil
+		}
+		if newName := strings.TrimSpace(tableCellAt(row, mapping.NewName)); newName != "" {
+			data = append(data, ExcelData{Row: index, NewName: newName})
+		}
+		return nil
+	}, progressCallback...)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("表格中没有新文件名")
+	}
+	return data, nil
+}
+
+// ReadTTSTable 按列映射逐行读取文字转语音表格，空白单元格会被忽略
+func ReadTTSTable(path string, mapping ColumnMapping, progressCallback ...ProgressCallback) ([]string, error) {
+	var texts []string
+	err := StreamTTSTable(path, mapping, func(row int, text string) error {
+		texts = append(texts, text)
+		return nil
+	}, progressCallback...)
+	if err != nil {
+		return nil, err
+	}
+	return texts, nil
+}
+
+// StreamTTSTable 与 ReadTTSTable 相同，但每读到一行文本就连同行号交给 fn 处理，不收集成完整的列表；
+// 读完后表格中没有文本时返回错误
+func StreamTTSTable(path string, mapping ColumnMapping, fn func(row int, text string) error, progressCallback ...ProgressCallback) error {
+	if err := mapping.Validate(MappingTTS); err != nil {
+		return err
+	}
+	dataRows, textRows := 0, 0
+	err := StreamTableSheet(path, mapping.Sheet, func(index int, row []string) error {
+		if index <= mapping.HeaderRow {
+			return nil
+		}
+		dataRows++
+		if text := strings.TrimSpace(tableCellAt(row, mapping.Text)); text != "" {
+			textRows++
+			return fn(index, text)
+		}
+		return nil
+	}, progressCallback...)
+	if err != nil {
+		return err
+	}
+	if dataRows == 0 {
+		return fmt.Errorf("表格中没有有效的文本数据")
+	}
+	if textRows == 0 {
+		return fmt.Errorf("未找到有效的文本内容")
+	}
+	return nil
+}
+
+// AutoColumnMapping 确定表格使用的列映射：优先使用为该工作簿记住的设置，
+// 其次根据第一个工作表的标题自动识别，都没有时使用默认映射
+func AutoColumnMapping(path, purpose string) (ColumnMapping, MappingSource, error) {
+	if mapping, ok := LoadColumnMapping(path, purpose); ok {
+		return mapping, MappingRemembered, nil
+	}
+	rows, err := ReadTableHead(path, "", detectHeaderRows)
+	if err != nil {
+		return DefaultColumnMapping(), MappingDefault, err
+	}
+	mapping, ok := DetectColumnMapping(rows, purpose)
+	if ok {
+		return mapping, MappingDetected, nil
+	}
+	return mapping, MappingDefault, nil
+}
+
+// columnMappingsPath 返回记住的列映射的存放位置
+func columnMappingsPath() (string, error) {
+	dir, err := appConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "column_mappings.json"), nil
+}
+
+// columnMappingKey 记住列映射时使用的键：用途加工作簿的绝对路径
+func columnMappingKey(path, purpose string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return purpose + ":" + path
+}
+
+// loadColumnMappings 读取所有记住的列映射，文件不存在时返回空表
+func loadColumnMappings() (map[string]ColumnMapping, error) {
+	mappings := make(map[string]ColumnMapping)
+	path, err := columnMappingsPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return mappings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取列映射设置失败: %v", err)
+	}
+	if err := json.Unmarshal(content, &mappings); err != nil {
+		return nil, fmt.Errorf("解析列映射设置失败: %v", err)
+	}
+	return mappings, nil
+}
+
+// LoadColumnMapping 读取为该工作簿记住的列映射
+func LoadColumnMapping(path, purpose string) (ColumnMapping, bool) {
+	mappings, err := loadColumnMappings()
+	if err != nil {
+		return ColumnMapping{}, false
+	}
+	mapping, ok := mappings[columnMappingKey(path, purpose)]
+	return mapping, ok
+}
+
+// SaveColumnMapping 为该工作簿记住列映射，下次选择同一个文件时自动使用
+func SaveColumnMapping(path, purpose string, mapping ColumnMapping) error {
+	if err := mapping.Validate(purpose); err != nil {
+		return err
+	}
+	mappings, err := loadColumnMappings()
+	if err != nil {
+		return err
+	}
+	mappings[columnMappingKey(path, purpose)] = mapping
+
+	content, err := json.MarshalIndent(mappings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化列映射设置失败: %v", err)
+	}
+	settingsPath, err := columnMappingsPath()
+	if err != nil {
+		return err
+	}
+	return writeConfigFile(settingsPath, content)
+}
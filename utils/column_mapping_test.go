@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// writeTestTable 在临时文件夹中写入 CSV 表格，返回表格路径
+func writeTestTable(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "table.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPlanRenameTableMatchesPlanRename(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, testFolder)
+	table := writeTestTable(t, "原文件名,新文件名\na,b\nb,a\nc,d\nx,y\n,\ne,e\n")
+	mapping := DefaultColumnMapping()
+
+	data, err := ReadRenameTable(table, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := PlanRename(dir, data, RenameOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := PlanRenameTable(dir, table, mapping, RenameOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Items) != len(want.Items) {
+		t.Fatalf("计划有 %d 行，应为 %d 行", len(got.Items), len(want.Items))
+	}
+	for i := range want.Items {
+		if got.Items[i] != want.Items[i] {
+			t.Errorf("第 %d 项为 %+v，应为 %+v", i, got.Items[i], want.Items[i])
+		}
+	}
+}
+
+func TestStreamTTSTableStopsWhenRowFails(t *testing.T) {
+	table := writeTestTable(t, "文本\n第一句\n \n第二句\n第三句\n")
+	stop := errors.New("stop")
+	var rows []int
+	err := StreamTTSTable(table, DefaultColumnMapping(), func(row int, text string) error {
+		rows = append(rows, row)
+		if text == "第二句" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("错误为 %v，应为 %v", err, stop)
+	}
+	if len(rows) != 2 || rows[0] != 2 || rows[1] != 4 {
+		t.Fatalf("处理的行为 %v，应为 [2 4]", rows)
+	}
+}
+
+func TestAutoColumnMappingDetectsAndRemembers(t *testing.T) {
+	useTempConfig(t)
+	path := filepath.Join(t.TempDir(), "jobs.xlsx")
+	f := excelize.NewFile()
+	f.SetSheetRow("Sheet1", "A1", &[]interface{}{"说明"})
+	f.SetSheetRow("Sheet1", "A3", &[]interface{}{"序号", "新 文件名", "原文件名", "文本"})
+	f.SetSheetRow("Sheet1", "A4", &[]interface{}{"1", "b.mp3", "a.mp3", "你好"})
+	f.SetSheetRow("Sheet1", "A5", &[]interface{}{"2"})
+	f.NewSheet("任务2")
+	f.SetSheetRow("任务2", "A1", &[]interface{}{"x", "y"})
+	f.SetSheetRow("任务2", "A2", &[]interface{}{"c", "d"})
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// 标题不在第一行、列的顺序与默认不同时按标题识别
+	mapping, source, err := AutoColumnMapping(path, MappingRename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source != MappingDetected || mapping.HeaderRow != 3 || mapping.OldName != 2 || mapping.NewName != 1 {
+		t.Fatalf("识别结果为 %+v（来源 %v），应识别出第 3 行标题", mapping, source)
+	}
+	data, err := ReadExcelForRename(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []ExcelData{{Row: 4, OldName: "a.mp3", NewName: "b.mp3"}}; !reflect.DeepEqual(data, want) {
+		t.Fatalf("读取到 %+v，应为 %+v", data, want)
+	}
+	texts, err := ReadExcelForTTS(path)
+	if err != nil || !reflect.DeepEqual(texts, []string{"你好"}) {
+		t.Fatalf("读取到的文本为 %v, %v，应为 [你好]", texts, err)
+	}
+
+	// 保存的设置优先于自动识别，并且只作用于对应的用途
+	saved := ColumnMapping{Sheet: "任务2", HeaderRow: 1, OldName: 0, NewName: 1, Operation: -1, Text: -1}
+	if err := SaveColumnMapping(path, MappingRename, saved); err != nil {
+		t.Fatal(err)
+	}
+	mapping, source, err = AutoColumnMapping(path, MappingRename)
+	if err != nil || source != MappingRemembered || mapping != saved {
+		t.Fatalf("读取到的设置为 %+v（来源 %v, %v），应为保存的 %+v", mapping, source, err, saved)
+	}
+	data, err = ReadRenameTable(path, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []ExcelData{{Row: 2, OldName: "c", NewName: "d"}}; !reflect.DeepEqual(data, want) {
+		t.Fatalf("工作表 任务2 读取到 %+v，应为 %+v", data, want)
+	}
+	if _, source, _ := AutoColumnMapping(path, MappingTTS); source != MappingDetected {
+		t.Fatalf("朗读用途的来源为 %v，应为自动识别", source)
+	}
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CopyMethod 复制模式下生成副本的方式
+type CopyMethod int
+
+const (
+	CopyFull     CopyMethod = iota // 完整复制文件内容并校验
+	CopyReflink                    // 写时复制克隆（Btrfs、XFS、APFS 等），不额外占用空间，不支持时完整复制
+	CopyHardLink                   // 硬链接，与原文件共用同一份数据，不在同一文件系统时完整复制
+)
+
+// CopyMethods 按界面显示顺序列出所有复制方式
+var CopyMethods = []CopyMethod{CopyFull, CopyReflink, CopyHardLink}
+
+var copyMethodNames = map[CopyMethod]string{
+	CopyFull:     "完整复制",
+	CopyReflink:  "写时复制（reflink）",
+	CopyHardLink: "硬链接",
+}
+
+// String 返回复制方式的中文名称
+func (m CopyMethod) String() string {
+	if name, ok := copyMethodNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知方式(%d)", int(m))
+}
+
+// ParseCopyMethod 根据中文名称解析复制方式
+func ParseCopyMethod(name string) (CopyMethod, error) {
+	for method, methodName := range copyMethodNames {
+		if methodName == name {
+			return method, nil
+		}
+	}
+	return CopyFull, fmt.Errorf("未知的复制方式: %s", name)
+}
+
+// copyFile 按复制方式在 to 生成 from 的副本，已存在的 to 会被替换；
+// 硬链接或写时复制不可用时改为完整复制，返回的说明描述实际使用的方式
+func copyFile(from, to string, method CopyMethod, progress CopyProgressCallback) (string, error) {
+	var note string
+	switch method {
+	case CopyHardLink:
+		err := linkFile(from, to)
+		if err == nil {
+			return "已创建硬链接", nil
+		}
+		note = fmt.Sprintf("无法创建硬链接（%v），已完整复制并校验", err)
+	case CopyReflink:
+		err := reflinkCopy(from, to)
+		if err == nil {
+			return "已创建写时复制副本", nil
+		}
+		note = fmt.Sprintf("无法写时复制（%v），已完整复制并校验", err)
+	default:
+		note = "已复制并校验"
+	}
+	if err := copyVerified(from, to, progress); err != nil {
+		return "", fmt.Errorf("复制文件失败: %w", err)
+	}
+	return note, nil
+}
+
+// linkFile 先在目标目录中创建指向原文件的临时硬链接，再改为目标文件名
+func linkFile(from, to string) error {
+	tmp := tempRenamePath(to)
+	if err := os.Link(from, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, to); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// reflinkCopy 用写时复制克隆文件到临时文件，保留权限和修改时间后改为目标文件名；
+// 克隆由文件系统保证内容一致，只核对文件大小，不再逐字节校验
+func reflinkCopy(from, to string) error {
+	info, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s 不是普通文件", filepath.Base(from))
+	}
+
+	tmp := tempRenamePath(to)
+	if err := reflinkFile(from, tmp); err != nil {
+		return err
+	}
+	err = os.Chmod(tmp, info.Mode().Perm())
+	if err == nil {
+		err = os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	}
+	if err == nil {
+		var cloned os.FileInfo
+		if cloned, err = os.Stat(tmp); err == nil && cloned.Size() != info.Size() {
+			err = fmt.Errorf("克隆后的文件大小不一致")
+		}
+	}
+	if err == nil {
+		err = os.Rename(tmp, to)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// FileListOptions 导出文件清单的选项
+type FileListOptions struct {
+	Recursive bool // 包含子文件夹中的文件，文件名写成“子文件夹/文件名”形式的相对路径
+	Size      bool // 附加文件大小列（字节）
+	ModTime   bool // 附加修改时间列
+	Extension bool // 附加扩展名列
+}
+
+// fileListEntry 文件清单中的一个文件
+type fileListEntry struct {
+	name string // 相对目标文件夹的路径，使用 / 分隔
+	info fs.FileInfo
+}
+
+// ExportFileList 扫描文件夹，把文件名按自然顺序写成 ReadExcelForRename 可以直接读取的表格：
+// A 列为当前文件名，B 列初始为相同的文件名供编辑，附加列只供参考，读取时会被忽略。
+// 不包含隐藏文件，返回写入的文件数
+func ExportFileList(folderPath, outputPath string, options FileListOptions) (int, error) {
+	entries, err := scanFileList(folderPath, options.Recursive)
+	if err != nil {
+		return 0, err
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := f.GetSheetName(0)
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return 0, fmt.Errorf("创建文件清单失败: %v", err)
+	}
+
+	header := []interface{}{"旧文件名", "新文件名"}
+	if options.Size {
+		header = append(header, "大小（字节）")
+	}
+	if options.ModTime {
+		header = append(header, "修改时间")
+	}
+	if options.Extension {
+		header = append(header, "扩展名")
+	}
+	sw.SetColWidth(1, 2, 40)
+	if len(header) > 2 {
+		sw.SetColWidth(3, len(header), 20)
+	}
+	sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+	if err := sw.SetRow("A1", header); err != nil {
+		return 0, fmt.Errorf("写入文件清单失败: %v", err)
+	}
+
+	for i, entry := range entries {
+		row := []interface{}{entry.name, entry.name}
+		if options.Size {
+			row = append(row, entry.info.Size())
+		}
+		if options.ModTime {
+			row = append(row, entry.info.ModTime().Format("2006-01-02 15:04:05"))
+		}
+		if options.Extension {
+			row = append(row, strings.TrimPrefix(filepath.Ext(entry.name), "."))
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := sw.SetRow(cell, row); err != nil {
+			return 0, fmt.Errorf("写入文件清单失败: %v", err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		return 0, fmt.Errorf("写入文件清单失败: %v", err)
+	}
+	if err := f.SaveAs(outputPath); err != nil {
+		return 0, fmt.Errorf("保存文件清单失败: %v", err)
+	}
+	return len(entries), nil
+}
+
+// scanFileList 列出文件夹中的文件并按自然顺序排列，递归时跳过隐藏的子文件夹
+func scanFileList(folderPath string, recursive bool) ([]fileListEntry, error) {
+	var entries []fileListEntry
+	err := filepath.WalkDir(folderPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == folderPath {
+			return nil
+		}
+		if d.IsDir() {
+			if !recursive || strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(folderPath, path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, fileListEntry{name: filepath.ToSlash(rel), info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("扫描文件夹失败: %v", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return NaturalLess(entries[i].name, entries[j].name)
+	})
+	return entries, nil
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExportFileList(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"10.mp3": "abc", "2.mp3": "abc", ".hidden": "abc", "sub/1.txt": "abc", ".git/x": "abc",
+	})
+	out := filepath.Join(t.TempDir(), "list.xlsx")
+
+	n, err := ExportFileList(dir, out, FileListOptions{Recursive: true, Size: true, Extension: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("导出了 %d 个文件，应为 3 个", n)
+	}
+	// 导出的清单可以直接作为重命名表格读取，附加列被忽略
+	data, err := ReadExcelForRename(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"2.mp3", "10.mp3", "sub/1.txt"}
+	if len(data) != len(want) {
+		t.Fatalf("读取到 %v，应为 %v", data, want)
+	}
+	for i, row := range data {
+		if row.OldName != want[i] || row.NewName != want[i] {
+			t.Errorf("第 %d 行为 %q -> %q，应为 %q -> %q", i+2, row.OldName, row.NewName, want[i], want[i])
+		}
+	}
+	f, err := excelize.OpenFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rows[3]; len(got) != 4 || got[2] != "3" || got[3] != "txt" {
+		t.Errorf("附加列为 %v，应包含大小 3 和扩展名 txt", got)
+	}
+
+	n, err = ExportFileList(dir, out, FileListOptions{})
+	if err != nil || n != 2 {
+		t.Fatalf("不含子文件夹时导出了 %d 个文件（%v），应为 2 个", n, err)
+	}
+}
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CopyProgressCallback 跨设备移动文件时报告复制进度，name 为正在复制的文件，copied 和 total 为字节数
+type CopyProgressCallback func(name string, copied, total int64)
+
+// copyBufferSize 复制文件时每次读写的字节数，也是报告字节进度的间隔
+const copyBufferSize = 1 << 20
+
+// moveFile 移动文件，目标在另一个磁盘或分区上导致无法直接重命名时，
+// 改为复制、校验后删除原文件；返回的说明非空时表示使用了复制的方式
+func moveFile(from, to string, progress CopyProgressCallback) (string, error) {
+	err := os.Rename(from, to)
+	if err == nil || !isCrossDevice(err) {
+		return "", err
+	}
+	if err := copyVerified(from, to, progress); err != nil {
+		return "", fmt.Errorf("跨设备移动失败: %w", err)
+	}
+	if info, statErr := os.Lstat(from); statErr == nil && info.IsDir() {
+		if err := os.RemoveAll(from); err != nil {
+			return fmt.Sprintf("跨设备移动：文件夹已复制到目标，但删除原文件夹失败（%v），原文件夹中可能还有未删除的文件", err), nil
+		}
+		return "跨设备移动：文件夹中的文件已逐一复制并校验，原文件夹已删除", nil
+	}
+	if err := os.Remove(from); err != nil {
+		return fmt.Sprintf("跨设备移动：已复制到目标，但删除原文件失败（%v），原文件仍保留", err), nil
+	}
+	return "跨设备移动：已复制并校验，原文件已删除", nil
+}
+
+// copyVerified 把文件复制到另一个文件系统：先写入目标目录中的临时文件，
+// 保留权限和修改时间，重新读出校验 SHA-256 与原文件一致后再改为目标文件名；
+// 符号链接按链接本身复制，文件夹按其中的每个文件逐一复制校验
+func copyVerified(from, to string, progress CopyProgressCallback) error {
+	info, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return copyTreeVerified(from, to, info, progress)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(from)
+		if err != nil {
+			return err
+		}
+		tmp := tempRenamePath(to)
+		if err := os.Symlink(link, tmp); err != nil {
+			return err
+		}
+		return os.Rename(tmp, to)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s 不是普通文件", filepath.Base(from))
+	}
+
+	tmp := tempRenamePath(to)
+	sourceSum, err := copyWithChecksum(from, tmp, info.Size(), progress)
+	if err == nil {
+		err = os.Chmod(tmp, info.Mode().Perm())
+	}
+	if err == nil {
+		err = os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	}
+	if err == nil {
+		var targetSum []byte
+		if targetSum, err = fileChecksum(tmp); err == nil && !bytes.Equal(sourceSum, targetSum) {
+			err = fmt.Errorf("复制后的文件校验不一致")
+		}
+	}
+	if err == nil {
+		err = os.Rename(tmp, to)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// copyTreeVerified 把文件夹复制到另一个文件系统：先在目标目录中建立临时文件夹，
+// 其中的文件和子文件夹逐一复制校验，全部完成后再改为目标文件夹名；失败时删除临时文件夹，原文件夹保持不变
+func copyTreeVerified(from, to string, info os.FileInfo, progress CopyProgressCallback) error {
+	tmp := tempRenamePath(to)
+	err := copyTreeInto(from, tmp, info, progress)
+	if err == nil {
+		err = os.Rename(tmp, to)
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	return nil
+}
+
+// copyTreeInto 新建文件夹 to 并复制 from 中的全部内容，最后恢复文件夹的权限和修改时间
+func copyTreeInto(from, to string, info os.FileInfo, progress CopyProgressCallback) error {
+	if err := os.Mkdir(to, 0700); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		source, target := filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name())
+		if !entry.IsDir() {
+			err = copyVerified(source, target, progress)
+		} else if entryInfo, statErr := entry.Info(); statErr != nil {
+			err = statErr
+		} else {
+			err = copyTreeInto(source, target, entryInfo, progress)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := os.Chmod(to, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(to, info.ModTime(), info.ModTime())
+}
+
+// copyWithChecksum 复制文件内容并同步到磁盘，返回复制时读到的原文件内容的 SHA-256
+func copyWithChecksum(from, to string, size int64, progress CopyProgressCallback) ([]byte, error) {
+	src, err := os.Open(from)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	name := filepath.Base(from)
+	var copied int64
+	buf := make([]byte, copyBufferSize)
+	for {
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			h.Write(buf[:n])
+			if _, err := dst.Write(buf[:n]); err != nil {
+				dst.Close()
+				return nil, err
+			}
+			copied += int64(n)
+			if progress != nil {
+				progress(name, copied, size)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			dst.Close()
+			return nil, readErr
+		}
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return nil, err
+	}
+	if err := dst.Close(); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// fileChecksum 计算文件内容的 SHA-256
+func fileChecksum(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
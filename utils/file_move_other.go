@@ -0,0 +1,18 @@
+//go:build !windows
+
+package utils
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isCrossDevice 判断重命名失败是否因为目标在另一个磁盘或分区上
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
+
+// isBusy 判断失败是否因为文件正被其他进程使用，这类错误稍后重试通常就能成功
+func isBusy(err error) bool {
+	return errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.ETXTBSY) || errors.Is(err, syscall.EAGAIN)
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCopyVerifiedCopiesFolders(t *testing.T) {
+	dir := t.TempDir()
+	from := filepath.Join(dir, "album")
+	writeTestFiles(t, from, map[string]string{"01.mp3": "one", "disc2/02.mp3": "two"})
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, path := range []string{filepath.Join(from, "disc2"), from} {
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	to := filepath.Join(dir, "moved")
+	if err := copyVerified(from, to, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkTestFiles(t, to, map[string]string{"01.mp3": "one", "disc2/02.mp3": "two"})
+	for _, path := range []string{to, filepath.Join(to, "disc2")} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("%s 的修改时间为 %v，应为 %v", path, info.ModTime(), mtime)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("复制后文件夹中有 %d 项，不应留下临时文件夹", len(entries))
+	}
+}
+
+func TestMoveFolderAcrossDevices(t *testing.T) {
+	other, err := os.MkdirTemp("/dev/shm", "move")
+	if err != nil {
+		t.Skip("没有可用的其他文件系统: ", err)
+	}
+	defer os.RemoveAll(other)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"probe": ""})
+	if err := os.Rename(filepath.Join(dir, "probe"), filepath.Join(other, "probe")); err == nil || !isCrossDevice(err) {
+		t.Skip("/dev/shm 与临时文件夹在同一个文件系统上")
+	}
+	from := filepath.Join(dir, "album")
+	writeTestFiles(t, from, map[string]string{"01.mp3": "one", "disc2/02.mp3": "two"})
+
+	to := filepath.Join(other, "album")
+	note, err := moveFile(from, to, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note == "" {
+		t.Error("跨设备移动应返回说明")
+	}
+	checkTestFiles(t, to, map[string]string{"01.mp3": "one", "disc2/02.mp3": "two"})
+	if _, err := os.Lstat(from); !os.IsNotExist(err) {
+		t.Errorf("原文件夹应已删除: %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice Windows 下移动到其他磁盘时 MoveFileEx 返回的 ERROR_NOT_SAME_DEVICE
+const errorNotSameDevice = syscall.Errno(17)
+
+// 文件被其他进程打开或锁定时返回的错误：ERROR_SHARING_VIOLATION、ERROR_LOCK_VIOLATION 和 ERROR_USER_MAPPED_FILE
+const (
+	errorSharingViolation = syscall.Errno(32)
+	errorLockViolation    = syscall.Errno(33)
+	errorUserMappedFile   = syscall.Errno(1224)
+)
+
+// isCrossDevice 判断重命名失败是否因为目标在另一个磁盘或分区上
+func isCrossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice) || errors.Is(err, syscall.EXDEV)
+}
+
+// isBusy 判断失败是否因为文件正被其他进程使用，这类错误稍后重试通常就能成功
+func isBusy(err error) bool {
+	return errors.Is(err, errorSharingViolation) || errors.Is(err, errorLockViolation) || errors.Is(err, errorUserMappedFile)
+}
@@ -1,148 +1,55 @@
 package utils
 
-import (
-	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
-
-	"github.com/xuri/excelize/v2"
-)
-
 // ExcelData stores Excel file data
 type ExcelData struct {
-	OldName string
-	NewName string
+	Row       int // Excel中的行号（从1开始，含标题行）
+	OldName   string
+	NewName   string
+	Problem   string // 无法生成新文件名的原因，非空时该行只在预览中报告，不会执行
+	Operation string // 操作列的内容，如 移动、复制、删除、新建文件夹，为空时为重命名
 }
 
 // ReadExcelForRename reads rename data from Excel file
-func ReadExcelForRename(filePath string) ([]ExcelData, error) {
-	f, err := excelize.OpenFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("打开Excel文件失败: %v", err)
-	}
-	defer f.Close()
-
-	// Get first worksheet
-	sheetName := f.GetSheetName(0)
-	rows, err := f.GetRows(sheetName)
+// 除 .xlsx 外也可读取 CSV、TSV、ODS、JSON 和 YAML 格式的表格；
+// 读取第一个工作表，能识别出“原文件名”“新文件名”等标题时按标题取列，否则使用 A、B 列并跳过第 1 行
+func ReadExcelForRename(filePath string, progressCallback ...ProgressCallback) ([]ExcelData, error) {
+	rows, err := ReadTableHead(filePath, "", detectHeaderRows)
 	if err != nil {
-		return nil, fmt.Errorf("读取工作表失败: %v", err)
+		return nil, err
 	}
-
-	var data []ExcelData
-	// Skip header, start from second row
-	for i := 1; i < len(rows); i++ {
-		row := rows[i]
-		if len(row) >= 2 {
-			data = append(data, ExcelData{
-				OldName: row[0],
-				NewName: row[1],
-			})
-		}
-	}
-
-	return data, nil
+	mapping, _ := DetectColumnMapping(rows, MappingRename)
+	return ReadRenameTable(filePath, mapping, progressCallback...)
 }
 
 // ProgressCallback 进度回调函数类型
 type ProgressCallback func(current, total int, percentage float64)
 
 // RenameFiles batch renames files and returns a list of failures
+// 先生成重命名计划，再按依赖顺序执行，交换、循环和链式重命名不会覆盖文件
 func RenameFiles(folderPath string, renameData []ExcelData, progressCallback ...ProgressCallback) error {
-	var failures []string
-	total := len(renameData)
-
-	for i, data := range renameData {
-		oldPath := filepath.Join(folderPath, data.OldName)
-		newPath := filepath.Join(folderPath, data.NewName)
-
-		// 计算进度百分比
-        percentage := float64(i) / float64(total) * 100
-		// 如果提供了回调函数，则调用它
-        if len(progressCallback) > 0 && progressCallback[0] != nil {
-            progressCallback[0](i, total, percentage)
-        }
-
-		// Check if source file exists
-		if _, err := os.Stat(oldPath); os.IsNotExist(err) {
-			failures = append(failures, fmt.Sprintf("文件不存在: %s", oldPath))
-			continue
-		}
-
-		// Execute rename
-		if err := os.Rename(oldPath, newPath); err != nil {
-			failures = append(failures, fmt.Sprintf("重命名失败 %s -> %s: %v", oldPath, newPath, err))
-			continue
-		}
-		
-	}
-
-	// 完成时调用回调函数，进度为100%
-	if len(progressCallback) > 0 && progressCallback[0] != nil {
-		progressCallback[0](total, total, 100.0)
+	results, err := RenameFilesWithOptions(folderPath, renameData, RenameOptions{}, progressCallback...)
+	if err != nil {
+		return err
 	}
-
-	// Return error with all failures if any
-	if len(failures) > 0 {
-		return fmt.Errorf("重命名过程中有 %d 个错误:\n%s", len(failures), strings.Join(failures, "\n"))
-	} 
-	return nil
+	return RenameFailures(results)
 }
 
-// ReadExcelForTTS reads TTS text data from Excel file
-func ReadExcelForTTS(filePath string) ([]string, error) {
-	// 验证文件路径
-	if filePath == "" {
-		return nil, fmt.Errorf("Excel文件路径不能为空")
-	}
-
-	// 检查文件是否存在
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("Excel文件不存在: %s", filePath)
-	}
-
-	// 打开Excel文件
-	f, err := excelize.OpenFile(filePath)
+// RenameFilesWithOptions 按指定选项批量重命名，返回每一行的结果
+func RenameFilesWithOptions(folderPath string, renameData []ExcelData, options RenameOptions, progressCallback ...ProgressCallback) ([]RenameResult, error) {
+	plan, err := PlanRename(folderPath, renameData, options)
 	if err != nil {
-		return nil, fmt.Errorf("打开Excel文件失败: %v", err)
-	}
-	defer f.Close()
-
-	// 获取第一个工作表
-	sheetName := f.GetSheetName(0)
-	if sheetName == "" {
-		return nil, fmt.Errorf("Excel文件中没有工作表")
+		return nil, err
 	}
+	return ExecuteRenamePlan(plan, progressCallback...)
+}
 
-	// 读取工作表内容
-	rows, err := f.GetRows(sheetName)
+// ReadExcelForTTS reads TTS text data from Excel file
+// 与 ReadExcelForRename 一样支持多种表格格式，能识别出“文本”等标题时按标题取列，否则使用 A 列
+func ReadExcelForTTS(filePath string, progressCallback ...ProgressCallback) ([]string, error) {
+	rows, err := ReadTableHead(filePath, "", detectHeaderRows)
 	if err != nil {
-		return nil, fmt.Errorf("读取工作表失败: %v", err)
+		return nil, err
 	}
-
-	// 检查是否有数据
-	if len(rows) <= 1 { // 考虑到标题行
-		return nil, fmt.Errorf("Excel文件中没有有效的文本数据")
-	}
-
-	var texts []string
-	// 从第二行开始读取（跳过标题行）
-	for i := 1; i < len(rows); i++ {
-		row := rows[i]
-		if len(row) >= 1 {
-			// 验证文本内容
-			text := strings.TrimSpace(row[0])
-			if text != "" {
-				texts = append(texts, text)
-			}
-		}
-	}
-
-	// 检查是否成功提取到文本
-	if len(texts) == 0 {
-		return nil, fmt.Errorf("未找到有效的文本内容")
-	}
-
-	return texts, nil
-}
\ No newline at end of file
+	mapping, _ := DetectColumnMapping(rows, MappingTTS)
+	return ReadTTSTable(filePath, mapping, progressCallback...)
+}
@@ -0,0 +1,329 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// TargetPlatform 文件名需要兼容的目标平台，文件重命名后可能被复制到这些平台上使用
+type TargetPlatform int
+
+const (
+	PlatformWindows TargetPlatform = iota // Windows（NTFS、SMB 共享）
+	PlatformMacOS                         // macOS（APFS、HFS+）
+	PlatformLinux                         // Linux（ext4 等）
+	PlatformFAT32                         // FAT32、exFAT 格式的 U 盘和存储卡
+)
+
+// TargetPlatforms 按界面显示顺序列出所有目标平台
+var TargetPlatforms = []TargetPlatform{PlatformWindows, PlatformMacOS, PlatformLinux, PlatformFAT32}
+
+var targetPlatformNames = map[TargetPlatform]string{
+	PlatformWindows: "Windows",
+	PlatformMacOS:   "macOS",
+	PlatformLinux:   "Linux",
+	PlatformFAT32:   "FAT32/U盘",
+}
+
+// String 返回目标平台的名称
+func (p TargetPlatform) String() string {
+	if name, ok := targetPlatformNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知平台(%d)", int(p))
+}
+
+// ParseTargetPlatform 根据名称解析目标平台
+func ParseTargetPlatform(name string) (TargetPlatform, error) {
+	for platform, platformName := range targetPlatformNames {
+		if platformName == name {
+			return platform, nil
+		}
+	}
+	return PlatformWindows, fmt.Errorf("未知的目标平台: %s", name)
+}
+
+// nameRules 一个平台对文件名的限制，长度都不含结尾的 NUL
+type nameRules struct {
+	invalid  string // 除路径分隔符和 NUL 以外不能出现在文件名中的字符
+	control  bool   // 不能包含 0x01-0x1F 控制字符
+	reserved bool   // 不能使用 CON、NUL 等设备名，带扩展名也不行
+	trailing bool   // 不能以点或空格结尾
+	bmpOnly  bool   // 只支持基本多文种平面内的字符，不支持表情符号等
+	utf16    bool   // 长度按 UTF-16 编码单元计算，否则按 UTF-8 字节计算
+	maxName  int    // 每一级名称的最大长度
+	maxPath  int    // 相对路径的最大长度，不含目标文件夹本身，复制到别处时目标位置的路径也会占用长度
+}
+
+var platformNameRules = map[TargetPlatform]nameRules{
+	PlatformWindows: {invalid: `<>:"|?*`, control: true, reserved: true, trailing: true, utf16: true, maxName: 255, maxPath: 259},
+	PlatformMacOS:   {invalid: ":", maxName: 255, maxPath: 1023},
+	PlatformLinux:   {maxName: 255, maxPath: 4095},
+	PlatformFAT32:   {invalid: `<>:"|?*`, control: true, reserved: true, trailing: true, bmpOnly: true, utf16: true, maxName: 255, maxPath: 259},
+}
+
+// reservedDeviceNames Windows 保留的设备名，比较时不区分大小写
+var reservedDeviceNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// length 按平台的计算方式返回名称的长度
+func (r nameRules) length(name string) int {
+	if r.utf16 {
+		return len(utf16.Encode([]rune(name)))
+	}
+	return len(name)
+}
+
+// invalidRune 判断字符能否出现在该平台的文件名中
+func (r nameRules) invalidRune(c rune) bool {
+	return c == 0 || c == '/' || c == '\\' || c == utf8.RuneError ||
+		r.control && c < 0x20 || r.bmpOnly && c > 0xFFFF || strings.ContainsRune(r.invalid, c)
+}
+
+// isReservedName 判断名称是否为保留的设备名，如 CON、con.txt、LPT1.tar.gz
+func isReservedName(name string) bool {
+	stem := name
+	if i := strings.IndexByte(stem, '.'); i >= 0 {
+		stem = stem[:i]
+	}
+	return reservedDeviceNames[strings.ToUpper(strings.TrimRight(stem, " "))]
+}
+
+// componentProblems 检查一级名称在一个平台上的问题
+func (r nameRules) componentProblems(name string) []string {
+	var problems []string
+	var invalid []string
+	seen := make(map[rune]bool)
+	for _, c := range name {
+		if r.invalidRune(c) && !seen[c] {
+			seen[c] = true
+			if c < 0x20 || c == utf8.RuneError {
+				invalid = append(invalid, fmt.Sprintf("%U", c))
+			} else {
+				invalid = append(invalid, string(c))
+			}
+		}
+	}
+	if len(invalid) > 0 {
+		problems = append(problems, fmt.Sprintf("“%s”包含不允许的字符 %s", name, strings.Join(invalid, " ")))
+	}
+	if r.reserved && isReservedName(name) {
+		problems = append(problems, fmt.Sprintf("“%s”是系统保留的设备名", name))
+	}
+	if r.trailing && strings.TrimRight(name, ". ") != name {
+		problems = append(problems, fmt.Sprintf("“%s”以点或空格结尾", name))
+	}
+	if n := r.length(name); n > r.maxName {
+		problems = append(problems, fmt.Sprintf("“%s”过长（%d，最多 %d）", name, n, r.maxName))
+	}
+	return problems
+}
+
+// splitNameComponents 把相对路径拆为各级名称，跳过空名称以及 . 和 ..（由路径安全检查处理）
+func splitNameComponents(name string) []string {
+	var components []string
+	for _, component := range strings.Split(normalizeRelPath(name), string(filepath.Separator)) {
+		if component != "" && component != "." && component != ".." {
+			components = append(components, component)
+		}
+	}
+	return components
+}
+
+// ValidateFileName 检查相对路径中的每一级名称以及路径总长度在目标平台上是否可用，
+// 返回所有问题的说明，同一个问题出现在多个平台时合并为一条
+func ValidateFileName(name string, platforms []TargetPlatform) []string {
+	var order []string
+	byProblem := make(map[string][]string)
+	for _, platform := range platforms {
+		rules, ok := platformNameRules[platform]
+		if !ok {
+			continue
+		}
+		var problems []string
+		for _, component := range splitNameComponents(name) {
+			problems = append(problems, rules.componentProblems(component)...)
+		}
+		if n := rules.length(filepath.ToSlash(filepath.Clean(normalizeRelPath(name)))); n > rules.maxPath {
+			problems = append(problems, fmt.Sprintf("路径过长（%d，最多 %d）", n, rules.maxPath))
+		}
+		for _, problem := range problems {
+			if _, ok := byProblem[problem]; !ok {
+				order = append(order, problem)
+			}
+			byProblem[problem] = append(byProblem[problem], platform.String())
+		}
+	}
+
+	result := make([]string, len(order))
+	for i, problem := range order {
+		result[i] = fmt.Sprintf("%s: %s", strings.Join(byProblem[problem], "、"), problem)
+	}
+	return result
+}
+
+// SanitizeFileComponent 把一级名称修正为在所有目标平台上都可用的名称：不允许的字符和路径分隔符替换为 _，
+// 去掉结尾的点和空格，保留的设备名后加 _，过长时保留扩展名截断主文件名，修正后为空时返回 _
+func SanitizeFileComponent(name string, platforms []TargetPlatform) string {
+	var b strings.Builder
+	for _, c := range name {
+		invalid := c == 0 || c == '/' || c == '\\' || c == utf8.RuneError
+		for _, platform := range platforms {
+			invalid = invalid || platformNameRules[platform].invalidRune(c)
+		}
+		if invalid {
+			b.WriteByte('_')
+		} else {
+			b.WriteRune(c)
+		}
+	}
+	name = b.String()
+
+	for _, platform := range platforms {
+		rules := platformNameRules[platform]
+		if rules.trailing {
+			name = strings.TrimRight(name, ". ")
+		}
+		if rules.reserved && isReservedName(name) {
+			if i := strings.IndexByte(name, '.'); i >= 0 {
+				name = name[:i] + "_" + name[i:]
+			} else {
+				name += "_"
+			}
+		}
+	}
+
+	// 按最严格的平台截断，扩展名本身过长时整体截断
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	fits := func(name string) bool {
+		for _, platform := range platforms {
+			if rules := platformNameRules[platform]; rules.length(name) > rules.maxName {
+				return false
+			}
+		}
+		return true
+	}
+	if !fits(ext) {
+		stem, ext = name, ""
+	}
+	for stem != "" && !fits(stem+ext) {
+		_, size := utf8.DecodeLastRuneInString(stem)
+		stem = stem[:len(stem)-size]
+	}
+	name = stem + ext
+
+	// 截断后可能又以点或空格结尾
+	for _, platform := range platforms {
+		if platformNameRules[platform].trailing {
+			name = strings.TrimRight(name, ". ")
+		}
+	}
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// SanitizeFileName 逐级修正相对路径中的名称，. 和 .. 保持不变，仍由路径安全检查拒绝
+func SanitizeFileName(name string, platforms []TargetPlatform) string {
+	components := strings.Split(normalizeRelPath(name), string(filepath.Separator))
+	for i, component := range components {
+		if component != "" && component != "." && component != ".." {
+			components[i] = SanitizeFileComponent(component, platforms)
+		}
+	}
+	return strings.Join(components, string(filepath.Separator))
+}
+
+// maxDiffCells 逐字比较时最长公共子序列表的最大格数，超过时不再逐字比较
+const maxDiffCells = 1 << 16
+
+// DescribeNameChange 逐字比较修正前后的名称，用“[原字符→新字符]”标出改动的部分，
+// 如 CON?.txt 修正为 CON_.txt 时返回 CON[?→_].txt。相同的开头和结尾不参与比较，
+// 中间改动的部分过长时不再逐字比较，整段标为 [原内容→新内容]
+func DescribeNameChange(before, after string) string {
+	a, b := []rune(before), []rune(after)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var out strings.Builder
+	out.WriteString(string(a[:prefix]))
+	tail := string(b[len(b)-suffix:])
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		fmt.Fprintf(&out, "[%s→%s]", string(a), string(b))
+	} else {
+		diffRunes(&out, a, b)
+	}
+	out.WriteString(tail)
+	return out.String()
+}
+
+// diffRunes 按最长公共子序列逐字比较，把结果写入 out
+func diffRunes(out *strings.Builder, a, b []rune) {
+	// 最长公共子序列，lcs[i][j] 为 a[i:] 与 b[j:] 的公共长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var removed, added strings.Builder
+	flush := func() {
+		if removed.Len() > 0 || added.Len() > 0 {
+			fmt.Fprintf(out, "[%s→%s]", removed.String(), added.String())
+			removed.Reset()
+			added.Reset()
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			flush()
+			out.WriteRune(a[i])
+			i, j = i+1, j+1
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			removed.WriteRune(a[i])
+			i++
+		default:
+			added.WriteRune(b[j])
+			j++
+		}
+	}
+	flush()
+}
+
+// truncateUTF8 按字节数截断字符串，不会截断在多字节字符的中间
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
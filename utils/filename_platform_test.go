@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDescribeNameChange(t *testing.T) {
+	tests := []struct {
+		before, after, want string
+	}{
+		{"CON?.txt", "CON_.txt", "CON[?→_].txt"},
+		{"a?b?c", "a_b_c", "a[?→_]b[?→_]c"},
+		{"name. ", "name", "name[. →]"},
+		{"CON", "CON_", "CON[→_]"},
+		{"dir/sub/a:b.txt", "dir/sub/a_b.txt", "dir/sub/a[:→_]b.txt"},
+	}
+	for _, tt := range tests {
+		if got := DescribeNameChange(tt.before, tt.after); got != tt.want {
+			t.Errorf("DescribeNameChange(%q, %q) = %q，应为 %q", tt.before, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeNameChangeLongNames(t *testing.T) {
+	// 只有一处改动的长路径只比较改动的部分
+	dir := strings.Repeat("很长的文件夹/", 2000)
+	start := time.Now()
+	if got, want := DescribeNameChange(dir+"a?.txt", dir+"a_.txt"), dir+"a[?→_].txt"; got != want {
+		t.Errorf("长路径的说明不正确: %q", got[len(got)-20:])
+	}
+
+	// 改动的部分过长时整段标出，不再逐字比较
+	before, after := strings.Repeat("甲?", 1000), strings.Repeat("乙_", 1000)
+	if got, want := DescribeNameChange("x"+before+"y", "x"+after+"y"), "x["+before+"→"+after+"]y"; got != want {
+		t.Error("改动过长时应整段标出")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("比较长名称用时 %v", elapsed)
+	}
+}
+
+func TestSanitizeFileComponent(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"CON", "CON_"},
+		{"con.txt", "con_.txt"},
+		{"LPT1.tar.gz", "LPT1_.tar.gz"},
+		{"CONSOLE.txt", "CONSOLE.txt"},
+		{"a?b:c.txt", "a_b_c.txt"},
+		{"a\tb", "a_b"},
+		{"name. ", "name"},
+		{"...", "_"},
+		{"😀.txt", "_.txt"},
+		{"ok.txt", "ok.txt"},
+	}
+	for _, tt := range tests {
+		got := SanitizeFileComponent(tt.name, TargetPlatforms)
+		if got != tt.want {
+			t.Errorf("SanitizeFileComponent(%q) = %q，应为 %q", tt.name, got, tt.want)
+		}
+		if problems := ValidateFileName(got, TargetPlatforms); len(problems) > 0 {
+			t.Errorf("%q 修正后仍不兼容: %v", tt.name, problems)
+		}
+	}
+	if got := SanitizeFileName("dir./../CON", TargetPlatforms); got != "dir/../CON_" {
+		t.Errorf("SanitizeFileName 结果为 %q，应为 %q", got, "dir/../CON_")
+	}
+
+	// 只针对 Windows 时保留表情符号；长度按各平台的计算方式截断并保留扩展名
+	if got := SanitizeFileComponent("😀.txt", []TargetPlatform{PlatformWindows}); got != "😀.txt" {
+		t.Errorf("只针对 Windows 时结果为 %q，应保持不变", got)
+	}
+	long := strings.Repeat("长", 200) + ".txt"
+	if got := SanitizeFileComponent(long, []TargetPlatform{PlatformLinux}); len(got) > 255 || !strings.HasSuffix(got, ".txt") {
+		t.Errorf("Linux 上截断后为 %d 字节，应不超过 255 字节并保留扩展名", len(got))
+	}
+	if got := SanitizeFileComponent(long, []TargetPlatform{PlatformWindows}); got != long {
+		t.Error("Windows 按 UTF-16 计算长度，204 个字符的文件名不应截断")
+	}
+}
+
+func TestPlanRenameChecksTargetPlatforms(t *testing.T) {
+	files := map[string]string{"a.txt": "A", "b.txt": "B"}
+	rows := []ExcelData{{OldName: "a.txt", NewName: "CON.txt"}, {OldName: "b.txt", NewName: "what?.txt"}}
+	windows := []TargetPlatform{PlatformWindows}
+
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, files)
+	plan, err := PlanRename(dir, rows, RenameOptions{Platforms: windows})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range plan.Items {
+		if item.Status != PlanIncompatibleName {
+			t.Errorf("第 %d 行计划状态为 %s，应为 %s", item.Row, item.Status, PlanIncompatibleName)
+		}
+	}
+
+	// 自动修正时按修正后的文件名执行，并说明改了哪里
+	plan, results, dir := runTestPlan(t, files, rows, RenameOptions{Platforms: windows, Sanitize: true})
+	for _, item := range plan.Items {
+		if item.Status != PlanOK || item.NameFix == "" {
+			t.Errorf("第 %d 行计划为 %s（修正说明 %q），应自动修正", item.Row, item.Status, item.NameFix)
+		}
+	}
+	checkStatuses(t, results, RenameSucceeded, RenameSucceeded)
+	checkTestFiles(t, dir, map[string]string{"CON_.txt": "A", "what_.txt": "B"})
+}
@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// ID3 标签中常用的字段名
+const (
+	ID3Title       = "title"
+	ID3Artist      = "artist"
+	ID3Album       = "album"
+	ID3AlbumArtist = "albumartist"
+	ID3Year        = "year"
+	ID3Track       = "track"
+	ID3Genre       = "genre"
+	ID3Comment     = "comment"
+)
+
+// id3v2Frames ID3v2 文本帧与字段名的对应关系，同时包含 v2.2 的三字符帧和 v2.3/v2.4 的四字符帧
+var id3v2Frames = map[string]string{
+	"TT2": ID3Title, "TIT2": ID3Title,
+	"TP1": ID3Artist, "TPE1": ID3Artist,
+	"TAL": ID3Album, "TALB": ID3Album,
+	"TP2": ID3AlbumArtist, "TPE2": ID3AlbumArtist,
+	"TYE": ID3Year, "TYER": ID3Year, "TDRC": ID3Year,
+	"TRK": ID3Track, "TRCK": ID3Track,
+	"TCO": ID3Genre, "TCON": ID3Genre,
+	"COM": ID3Comment, "COMM": ID3Comment,
+}
+
+// ReadID3Tags 读取 MP3 文件的 ID3 标签，优先使用 ID3v2，缺少的字段再从文件末尾的 ID3v1 补充。
+// 文件没有任何标签时返回空表
+func ReadID3Tags(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer f.Close()
+
+	tags := make(map[string]string)
+	if err := readID3v2(f, tags); err != nil {
+		return nil, err
+	}
+	if err := readID3v1(f, tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// readID3v2 读取文件开头的 ID3v2 标签
+func readID3v2(f *os.File, tags map[string]string) error {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(f, header); err != nil || string(header[:3]) != "ID3" {
+		return nil
+	}
+	version, flags := header[3], header[5]
+	if version < 2 || version > 4 {
+		return nil
+	}
+	body := make([]byte, syncsafe(header[6:10]))
+	if _, err := io.ReadFull(f, body); err != nil {
+		return fmt.Errorf("读取ID3标签失败: %v", err)
+	}
+	// v2.4 以前的反同步作用于整个标签，v2.4 改为逐帧标记
+	if flags&0x80 != 0 && version < 4 {
+		body = bytes.ReplaceAll(body, []byte{0xFF, 0x00}, []byte{0xFF})
+	}
+	if flags&0x40 != 0 && version > 2 && len(body) >= 4 {
+		size := int(binary.BigEndian.Uint32(body[:4])) + 4
+		if version == 4 {
+			size = syncsafe(body[:4])
+		}
+		if size > len(body) {
+			return nil
+		}
+		body = body[size:]
+	}
+
+	idLen, headerLen := 4, 10
+	if version == 2 {
+		idLen, headerLen = 3, 6
+	}
+	for len(body) >= headerLen && body[0] != 0 {
+		id := string(body[:idLen])
+		var size int
+		switch version {
+		case 2:
+			size = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 3:
+			size = int(binary.BigEndian.Uint32(body[4:8]))
+		default:
+			size = syncsafe(body[4:8])
+		}
+		if size > len(body)-headerLen {
+			break
+		}
+		frame := body[headerLen : headerLen+size]
+		var frameFlags byte
+		if version > 2 {
+			frameFlags = body[9]
+		}
+		body = body[headerLen+size:]
+
+		field, ok := id3v2Frames[id]
+		if !ok || tags[field] != "" {
+			continue
+		}
+		// 跳过压缩或加密的帧；v2.4 的帧可能单独反同步，或在正文前带 4 字节的长度
+		if (version == 3 && frameFlags&0xC0 != 0) || (version == 4 && frameFlags&0x0C != 0) {
+			continue
+		}
+		if version == 4 && frameFlags&0x02 != 0 {
+			frame = bytes.ReplaceAll(frame, []byte{0xFF, 0x00}, []byte{0xFF})
+		}
+		if version == 4 && frameFlags&0x01 != 0 {
+			if len(frame) < 4 {
+				continue
+			}
+			frame = frame[4:]
+		}
+		if len(frame) < 1 {
+			continue
+		}
+		encoding, text := frame[0], frame[1:]
+		if field == ID3Comment {
+			// 注释帧在正文前还有 3 字节语言和一段以 0 结尾的描述
+			if len(text) < 3 {
+				continue
+			}
+			_, text = splitID3Text(encoding, text[3:])
+		}
+		if value := strings.TrimSpace(decodeID3Text(encoding, text)); value != "" {
+			tags[field] = value
+		}
+	}
+	return nil
+}
+
+// readID3v1 读取文件末尾 128 字节的 ID3v1 标签，只补充 ID3v2 中没有的字段
+func readID3v1(f *os.File, tags map[string]string) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("读取文件信息失败: %v", err)
+	}
+	if info.Size() < 128 {
+		return nil
+	}
+	tag := make([]byte, 128)
+	if _, err := f.ReadAt(tag, info.Size()-128); err != nil {
+		return fmt.Errorf("读取ID3标签失败: %v", err)
+	}
+	if string(tag[:3]) != "TAG" {
+		return nil
+	}
+
+	fields := []struct {
+		name       string
+		start, end int
+	}{
+		{ID3Title, 3, 33},
+		{ID3Artist, 33, 63},
+		{ID3Album, 63, 93},
+		{ID3Year, 93, 97},
+		{ID3Comment, 97, 127},
+	}
+	for _, field := range fields {
+		if tags[field.name] != "" {
+			continue
+		}
+		raw := tag[field.start:field.end]
+		if i := bytes.IndexByte(raw, 0); i >= 0 {
+			raw = raw[:i]
+		}
+		if value := strings.TrimSpace(decodeID3Text(0, raw)); value != "" {
+			tags[field.name] = value
+		}
+	}
+	// ID3v1.1 在注释的最后一个字节中存放音轨号
+	if tags[ID3Track] == "" && tag[125] == 0 && tag[126] != 0 {
+		tags[ID3Track] = fmt.Sprint(tag[126])
+	}
+	return nil
+}
+
+// syncsafe 解析 ID3v2 中每字节只用低 7 位的整数
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// splitID3Text 在第一个字符串结束符处拆分文本，UTF-16 编码的结束符为两个 0 字节
+func splitID3Text(encoding byte, text []byte) ([]byte, []byte) {
+	if encoding == 1 || encoding == 2 {
+		for i := 0; i+1 < len(text); i += 2 {
+			if text[i] == 0 && text[i+1] == 0 {
+				return text[:i], text[i+2:]
+			}
+		}
+		return text, nil
+	}
+	if i := bytes.IndexByte(text, 0); i >= 0 {
+		return text[:i], text[i+1:]
+	}
+	return text, nil
+}
+
+// decodeID3Text 按 ID3 文本编码解码：0 为 ISO-8859-1，1 为带 BOM 的 UTF-16，2 为 UTF-16BE，3 为 UTF-8。
+// 国内很多软件在 ISO-8859-1 的位置写入 GBK 编码的中文，此时按 GBK 解码
+func decodeID3Text(encoding byte, text []byte) string {
+	// 多值文本帧以 0 分隔，只取第一个值
+	text, _ = splitID3Text(encoding, text)
+	switch encoding {
+	case 1, 2:
+		bigEndian := encoding == 2
+		if len(text) >= 2 && text[0] == 0xFE && text[1] == 0xFF {
+			bigEndian, text = true, text[2:]
+		} else if len(text) >= 2 && text[0] == 0xFF && text[1] == 0xFE {
+			bigEndian, text = false, text[2:]
+		}
+		units := make([]uint16, len(text)/2)
+		for i := range units {
+			if bigEndian {
+				units[i] = binary.BigEndian.Uint16(text[2*i:])
+			} else {
+				units[i] = binary.LittleEndian.Uint16(text[2*i:])
+			}
+		}
+		return string(utf16.Decode(units))
+	case 3:
+		return string(text)
+	}
+
+	if utf8.Valid(text) {
+		return string(text)
+	}
+	if decoded, err := simplifiedchinese.GBK.NewDecoder().Bytes(text); err == nil && !bytes.ContainsRune(decoded, utf8.RuneError) {
+		return string(decoded)
+	}
+	runes := make([]rune, len(text))
+	for i, b := range text {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
@@ -0,0 +1,8 @@
+package utils
+
+import "golang.org/x/sys/unix"
+
+// reflinkFile 用 clonefile 把 from 克隆为新文件 to，APFS 支持
+func reflinkFile(from, to string) error {
+	return unix.Clonefile(from, to, unix.CLONE_NOFOLLOW)
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package utils
+
+import "errors"
+
+// reflinkFile 当前系统不支持写时复制
+func reflinkFile(from, to string) error {
+	return errors.New("当前系统不支持写时复制")
+}
@@ -0,0 +1,488 @@
+package utils
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BackupMode 执行重命名前的备份方式
+type BackupMode int
+
+const (
+	BackupNone         BackupMode = iota // 不备份，只写重命名日志
+	BackupFull                           // 把受影响的文件完整打包进压缩包
+	BackupManifestOnly                   // 只保存文件名清单和校验值，恢复时按校验值找回文件
+)
+
+// BackupModes 按界面显示顺序列出所有备份方式
+var BackupModes = []BackupMode{BackupNone, BackupFull, BackupManifestOnly}
+
+var backupModeNames = map[BackupMode]string{
+	BackupNone:         "不备份",
+	BackupFull:         "完整备份文件",
+	BackupManifestOnly: "仅备份清单和校验值",
+}
+
+// String 返回备份方式的中文名称
+func (m BackupMode) String() string {
+	if name, ok := backupModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知方式(%d)", int(m))
+}
+
+// ParseBackupMode 根据中文名称解析备份方式
+func ParseBackupMode(name string) (BackupMode, error) {
+	for mode, modeName := range backupModeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+	return BackupNone, fmt.Errorf("未知的备份方式: %s", name)
+}
+
+// backupManifestName 压缩包中清单文件的名称
+const backupManifestName = "manifest.json"
+
+// BackupEntry 备份清单中的一个文件
+type BackupEntry struct {
+	Path    string      `json:"path"`             // 执行前的完整路径
+	Target  string      `json:"target,omitempty"` // 执行后文件应在的完整路径，删除和被覆盖的文件为空
+	Size    int64       `json:"size"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"mod_time"`
+	SHA256  string      `json:"sha256,omitempty"` // 文件内容的校验值，符号链接为空
+	Link    string      `json:"link,omitempty"`   // 符号链接指向的位置
+	Stored  string      `json:"stored,omitempty"` // 完整备份时文件在压缩包中的名称
+}
+
+// BackupManifest 一次备份的清单
+type BackupManifest struct {
+	Time    time.Time     `json:"time"`
+	Folder  string        `json:"folder"`
+	Mode    BackupMode    `json:"mode"`
+	Entries []BackupEntry `json:"entries"`
+}
+
+// RenameBackupDir 返回备份压缩包的存放目录
+func RenameBackupDir() (string, error) {
+	dir, err := appConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rename_backups"), nil
+}
+
+// backupEntries 列出执行计划会移走、删除或覆盖的文件：重命名、移动和删除的原文件以及将被覆盖的目标文件，
+// 文件夹展开为其中的每个文件；复制的原文件不会被修改，不需要备份
+func backupEntries(plan *RenamePlan) ([]BackupEntry, error) {
+	var entries []BackupEntry
+	add := func(path, target string) error {
+		return filepath.Walk(path, func(walked string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			entry := BackupEntry{Path: walked, Size: info.Size(), Mode: info.Mode(), ModTime: info.ModTime()}
+			if target != "" {
+				rel, _ := filepath.Rel(path, walked)
+				entry.Target = filepath.Join(target, rel)
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	}
+	for _, item := range plan.Items {
+		if !item.Runnable() {
+			continue
+		}
+		if plan.vacates(item) {
+			if err := add(item.OldPath, item.NewPath); err != nil {
+				return nil, err
+			}
+		}
+		if item.Status == PlanOverwrite {
+			if _, err := os.Lstat(item.NewPath); err == nil {
+				if err := add(item.NewPath, ""); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return entries, nil
+}
+
+// storedName 返回文件在压缩包中的名称：目标文件夹中的文件保持相对路径，其他位置的文件按序号存放
+func storedName(plan *RenamePlan, index int, path string) string {
+	if rel, err := filepath.Rel(plan.FolderPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return "files/" + filepath.ToSlash(rel)
+	}
+	if rel, err := filepath.Rel(plan.TargetFolder(), path); err == nil && !strings.HasPrefix(rel, "..") {
+		return "output/" + filepath.ToSlash(rel)
+	}
+	return fmt.Sprintf("other/%d/%s", index, filepath.Base(path))
+}
+
+// CreateRenameBackup 在执行计划前备份受影响的文件，生成以 id 命名的压缩包并返回其路径；
+// 完整备份时压缩包中包含文件内容，否则只包含清单和校验值。没有受影响的文件时返回空路径
+func CreateRenameBackup(plan *RenamePlan, id string, progressCallback ...ProgressCallback) (string, error) {
+	entries, err := backupEntries(plan)
+	if err != nil {
+		return "", fmt.Errorf("读取要备份的文件失败: %v", err)
+	}
+	if len(entries) == 0 {
+		return "", nil
+	}
+
+	dir, err := RenameBackupDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建备份目录失败: %v", err)
+	}
+
+	// 先写临时文件再改名，备份写到一半失败时不会留下不完整的压缩包
+	path := filepath.Join(dir, id+".zip")
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return "", fmt.Errorf("创建备份文件失败: %v", err)
+	}
+	err = writeBackupArchive(f, plan, entries, progressCallback...)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("写入备份文件失败: %v", err)
+	}
+	return path, nil
+}
+
+// writeBackupArchive 把文件（完整备份时）和清单写入压缩包，清单放在最后，记录每个文件的校验值
+func writeBackupArchive(w io.Writer, plan *RenamePlan, entries []BackupEntry, progressCallback ...ProgressCallback) error {
+	zw := zip.NewWriter(w)
+	total := len(entries)
+	for i := range entries {
+		entry := &entries[i]
+		if len(progressCallback) > 0 && progressCallback[0] != nil {
+			progressCallback[0](i, total, float64(i)/float64(total)*100)
+		}
+
+		if entry.Mode&os.ModeSymlink != 0 {
+			link, err := os.Readlink(entry.Path)
+			if err != nil {
+				return err
+			}
+			entry.Link = link
+			continue
+		}
+
+		h := sha256.New()
+		var dst io.Writer = h
+		if plan.Options.Backup == BackupFull {
+			entry.Stored = storedName(plan, i, entry.Path)
+			fw, err := zw.CreateHeader(&zip.FileHeader{Name: entry.Stored, Method: zip.Deflate, Modified: entry.ModTime})
+			if err != nil {
+				return err
+			}
+			dst = io.MultiWriter(fw, h)
+		}
+		src, err := os.Open(entry.Path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(dst, src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+		entry.SHA256 = hex.EncodeToString(h.Sum(nil))
+	}
+
+	manifest := BackupManifest{Time: time.Now(), Folder: plan.FolderPath, Mode: plan.Options.Backup, Entries: entries}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	fw, err := zw.Create(backupManifestName)
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(content); err != nil {
+		return err
+	}
+	if len(progressCallback) > 0 && progressCallback[0] != nil {
+		progressCallback[0](total, total, 100)
+	}
+	return zw.Close()
+}
+
+// ReadBackupManifest 读取备份压缩包中的清单
+func ReadBackupManifest(archivePath string) (*BackupManifest, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开备份文件失败: %v", err)
+	}
+	defer zr.Close()
+	return readBackupManifest(&zr.Reader)
+}
+
+// readBackupManifest 从已打开的压缩包中读取清单
+func readBackupManifest(zr *zip.Reader) (*BackupManifest, error) {
+	for _, file := range zr.File {
+		if file.Name != backupManifestName {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("读取备份清单失败: %v", err)
+		}
+		defer rc.Close()
+		var manifest BackupManifest
+		if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("解析备份清单失败: %v", err)
+		}
+		return &manifest, nil
+	}
+	return nil, fmt.Errorf("备份文件中缺少清单")
+}
+
+// backupMatches 判断 path 上的文件是否与备份时的文件相同
+func backupMatches(path string, entry BackupEntry) bool {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	if entry.Link != "" {
+		link, err := os.Readlink(path)
+		return err == nil && link == entry.Link
+	}
+	if !info.Mode().IsRegular() || info.Size() != entry.Size {
+		return false
+	}
+	sum, err := fileChecksum(path)
+	return err == nil && hex.EncodeToString(sum) == entry.SHA256
+}
+
+// checksumIndex 按文件大小索引文件夹中的文件，用于按校验值找回改过名的文件
+type checksumIndex struct {
+	bySize  map[int64][]string
+	claimed map[string]bool // 已被认领或不能移走的文件
+}
+
+// newChecksumIndex 遍历文件夹建立索引，跳过读不到的子文件夹
+func newChecksumIndex(folder string, claimed map[string]bool) *checksumIndex {
+	index := &checksumIndex{bySize: make(map[int64][]string), claimed: claimed}
+	filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			index.bySize[info.Size()] = append(index.bySize[info.Size()], path)
+		}
+		return nil
+	})
+	return index
+}
+
+// find 找到一个与备份时内容相同且尚未被认领的文件
+func (x *checksumIndex) find(entry BackupEntry) (string, bool) {
+	for _, path := range x.bySize[entry.Size] {
+		if !x.claimed[path] && backupMatches(path, entry) {
+			x.claimed[path] = true
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// RestoreRenameBackup 按备份恢复执行前的状态：文件仍在原位置且未改变时跳过，否则依次从执行后的位置、
+// 文件夹中校验值相同的文件以及压缩包中的备份找回；原位置已被其他文件占用时不会覆盖，而是报告出来
+func RestoreRenameBackup(archivePath string, progressCallback ...ProgressCallback) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("打开备份文件失败: %v", err)
+	}
+	defer zr.Close()
+	manifest, err := readBackupManifest(&zr.Reader)
+	if err != nil {
+		return err
+	}
+	stored := make(map[string]*zip.File)
+	for _, file := range zr.File {
+		stored[file.Name] = file
+	}
+
+	// 第一步：找出需要恢复的文件，仍在原位置的文件不能被当作其他文件的来源
+	var pending []int
+	claimed := make(map[string]bool)
+	for i, entry := range manifest.Entries {
+		if backupMatches(entry.Path, entry) {
+			claimed[entry.Path] = true
+		} else {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// 第二步：把在磁盘上找到的文件先移到临时文件名，交换和循环改名也能逐个放回原位置；
+	// 执行后的位置上没有时才遍历文件夹按校验值查找
+	var index *checksumIndex
+	var failures []string
+	sources := make(map[int]string)
+	for _, i := range pending {
+		entry := manifest.Entries[i]
+		found := ""
+		if entry.Target != "" && !claimed[entry.Target] && backupMatches(entry.Target, entry) {
+			found = entry.Target
+		} else if entry.Link == "" {
+			if index == nil {
+				index = newChecksumIndex(manifest.Folder, claimed)
+			}
+			found, _ = index.find(entry)
+		}
+		if found == "" {
+			continue
+		}
+		claimed[found] = true
+		tmp := tempRenamePath(found)
+		if err := os.Rename(found, tmp); err != nil {
+			failures = append(failures, fmt.Sprintf("移动 %s 失败: %v", found, err))
+			continue
+		}
+		sources[i] = tmp
+	}
+
+	// 第三步：放回原位置，磁盘上找不到的文件从压缩包中解压
+	for n, i := range pending {
+		entry := manifest.Entries[i]
+		if len(progressCallback) > 0 && progressCallback[0] != nil {
+			progressCallback[0](n, len(pending), float64(n)/float64(len(pending))*100)
+		}
+		if err := restoreBackupEntry(entry, sources[i], stored); err != nil {
+			failures = append(failures, fmt.Sprintf("恢复 %s 失败: %v", entry.Path, err))
+			if tmp, ok := sources[i]; ok {
+				failures = append(failures, fmt.Sprintf("文件暂存为 %s", tmp))
+			}
+		}
+	}
+	if len(progressCallback) > 0 && progressCallback[0] != nil {
+		progressCallback[0](len(pending), len(pending), 100)
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("恢复过程中有 %d 个问题:\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// RestoreJournalBackup 按一次重命名的备份恢复执行前的状态。备份中只有被移走、删除或覆盖的文件，
+// 全部恢复后再按日志删除该次复制出的文件和新建的文件夹，并把日志标记为已撤销
+func RestoreJournalBackup(journal *RenameJournal, progressCallback ...ProgressCallback) error {
+	if journal.Backup == "" {
+		return fmt.Errorf("该次重命名没有备份")
+	}
+	if err := RestoreRenameBackup(journal.Backup, progressCallback...); err != nil {
+		return err
+	}
+
+	// 重命名、移动和删除的文件已由备份恢复，只剩复制和新建文件夹需要按日志撤销
+	for i := range journal.Entries {
+		if entry := &journal.Entries[i]; entry.pending() && entry.Op != OpCopy && entry.Op != OpMkdir {
+			entry.Undone = true
+		}
+	}
+	failures := undoOtherOperations(journal)
+	if len(failures) == 0 {
+		now := time.Now()
+		journal.UndoneAt = &now
+	}
+	if err := SaveRenameJournal(journal); err != nil {
+		failures = append(failures, err.Error())
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("文件已按备份恢复，但有 %d 个问题:\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// FindBackupJournal 找到生成该备份压缩包的重命名日志，历史记录已丢失时返回 nil
+func FindBackupJournal(archivePath string) *RenameJournal {
+	journals, err := ListRenameJournals()
+	if err != nil {
+		return nil
+	}
+	for _, journal := range journals {
+		if journal.Backup != "" && filepath.Clean(journal.Backup) == filepath.Clean(archivePath) {
+			return journal
+		}
+	}
+	return nil
+}
+
+// restoreBackupEntry 把一个文件放回原位置：from 非空时移回找到的文件，否则从压缩包中恢复
+func restoreBackupEntry(entry BackupEntry, from string, stored map[string]*zip.File) error {
+	if _, err := os.Lstat(entry.Path); err == nil {
+		return fmt.Errorf("原位置已被其他文件占用")
+	}
+	if err := os.MkdirAll(filepath.Dir(entry.Path), 0755); err != nil {
+		return err
+	}
+	switch {
+	case from != "":
+		_, err := moveFile(from, entry.Path, nil)
+		return err
+	case entry.Link != "":
+		return os.Symlink(entry.Link, entry.Path)
+	case entry.Stored == "":
+		return fmt.Errorf("找不到该文件，且备份中只有清单，没有文件内容")
+	}
+
+	file, ok := stored[entry.Stored]
+	if !ok {
+		return fmt.Errorf("备份文件中缺少 %s", entry.Stored)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// 解压到临时文件并校验，确认无误后再改为原文件名
+	tmp := tempRenamePath(entry.Path)
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, entry.Mode.Perm())
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, h), rc)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && hex.EncodeToString(h.Sum(nil)) != entry.SHA256 {
+		err = fmt.Errorf("备份中的文件校验失败")
+	}
+	if err == nil {
+		os.Chmod(tmp, entry.Mode.Perm())
+		os.Chtimes(tmp, entry.ModTime, entry.ModTime)
+		err = os.Rename(tmp, entry.Path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
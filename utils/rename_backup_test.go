@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRestoreJournalBackupReturnsFolderToPreRunState(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	original := map[string]string{"a.txt": "a", "b.txt": "b", "old.txt": "old"}
+	writeTestFiles(t, dir, original)
+
+	results, err := RenameFilesWithOptions(dir, []ExcelData{
+		{Row: 2, NewName: "new", Operation: "新建文件夹"},
+		{Row: 3, OldName: "a.txt", NewName: "new/a copy.txt", Operation: "复制"},
+		{Row: 4, OldName: "b.txt", NewName: "x.txt"},
+		{Row: 5, OldName: "old.txt", NewName: "b.txt", Operation: "重命名"},
+	}, RenameOptions{Backup: BackupFull})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RenameFailures(results); err != nil {
+		t.Fatal(err)
+	}
+	checkTestFiles(t, dir, map[string]string{"a.txt": "a", "b.txt": "old", "new/a copy.txt": "a", "x.txt": "b"})
+
+	journal, err := LastRenameJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if journal.Backup == "" {
+		t.Fatal("执行前应生成备份")
+	}
+	if FindBackupJournal(journal.Backup) == nil {
+		t.Fatal("应能按备份文件找到重命名日志")
+	}
+	if err := RestoreJournalBackup(journal); err != nil {
+		t.Fatal(err)
+	}
+	checkTestFiles(t, dir, original)
+	if _, err := os.Stat(filepath.Join(dir, "new")); !os.IsNotExist(err) {
+		t.Fatalf("新建的文件夹应已删除: %v", err)
+	}
+	if !journal.Undone() {
+		t.Fatal("从备份恢复后日志应标记为已撤销")
+	}
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// NewNameComposer 按模板把表格一行中的多列组合为新文件名，如 {B}_{C:03}_{D}.{ext}。
+// 字段为大写的列名，格式为位数时按位数为数字补 0；{ext} 为原文件的扩展名（不含点），原文件没有扩展名时为空
+type NewNameComposer struct {
+	template *Template
+	columns  []int // 模板用到的列号（从 0 开始），按出现顺序去重
+}
+
+// ParseNewNameTemplate 解析新文件名模板并检查其中的列名和格式
+func ParseNewNameTemplate(source string) (*NewNameComposer, error) {
+	t, err := ParseTemplate(source)
+	if err != nil {
+		return nil, err
+	}
+	c := &NewNameComposer{template: t}
+	for _, field := range t.Fields() {
+		if field == "ext" {
+			continue
+		}
+		col, err := composeColumn(field)
+		if err != nil {
+			return nil, err
+		}
+		c.columns = append(c.columns, col)
+	}
+	if len(c.columns) == 0 {
+		return nil, fmt.Errorf("新文件名模板中至少要使用一列，如 {B}")
+	}
+	for _, part := range t.parts {
+		if part.fields != nil && part.format != "" {
+			if _, err := formatNumber("0", part.format); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// composeColumn 把模板字段解析为列号，只接受大写的列名，避免与 {ext} 等字段混淆
+func composeColumn(field string) (int, error) {
+	if field != strings.ToUpper(field) {
+		return 0, fmt.Errorf("新文件名模板中的列名应为大写字母，如 {B}: {%s}", field)
+	}
+	col, err := excelize.ColumnNameToNumber(field)
+	if err != nil {
+		return 0, fmt.Errorf("新文件名模板中的列名无效: {%s}", field)
+	}
+	return col - 1, nil
+}
+
+// Columns 返回模板用到的列号
+func (c *NewNameComposer) Columns() []int {
+	return c.columns
+}
+
+// Compose 用一行的单元格和原文件名生成新文件名，单元格中的路径分隔符会被替换掉；
+// 生成的文件名不带扩展名时自动补上原文件的扩展名，如原文件为 a.mp3、模板为 {B}_{C} 时生成 x_y.mp3。
+// 原文件没有扩展名时 {ext} 为空，其前面留在末尾的点会被去掉，如 {B}.{ext} 生成 x
+func (c *NewNameComposer) Compose(row []string, oldName string) (string, error) {
+	ext := strings.TrimPrefix(filepath.Ext(oldName), ".")
+	name, err := c.template.Execute(func(field, format string) (string, error) {
+		if field == "ext" {
+			return ext, nil
+		}
+		col, err := composeColumn(field)
+		if err != nil {
+			return "", err
+		}
+		value := strings.TrimSpace(tableCellAt(row, col))
+		if value == "" {
+			return "", ErrFieldMissing
+		}
+		value, err = formatNumber(value, format)
+		if err != nil {
+			return "", err
+		}
+		return pathSeparatorReplacer.Replace(value), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if ext == "" {
+		name = strings.TrimSuffix(name, ".")
+	}
+	return keepOriginalExt(oldName, name), nil
+}
+
+// blank 判断一行中模板用到的列是否全部为空
+func (c *NewNameComposer) blank(row []string) bool {
+	for _, col := range c.columns {
+		if strings.TrimSpace(tableCellAt(row, col)) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// keepOriginalExt 新文件名没有写扩展名时补上原文件的扩展名；写了扩展名时按写的为准，如 x.png 不会变成 x.png.jpg。
+// 文件名中的点不一定是扩展名，如 Dr. Who、Vol. 2，只有与原扩展名相同或看起来像扩展名的后缀才算
+func keepOriginalExt(oldName, newName string) string {
+	ext := filepath.Ext(oldName)
+	if ext == "" {
+		return newName
+	}
+	if newExt := filepath.Ext(newName); strings.EqualFold(newExt, ext) || looksLikeExt(newExt) {
+		return newName
+	}
+	return newName + ext
+}
+
+// maxGuessedExtLen 不认识的扩展名（不含点）最多几个字符，如 jpeg、xlsx
+const maxGuessedExtLen = 5
+
+// looksLikeExt 判断后缀是否像扩展名：点后是不超过 maxGuessedExtLen 个 ASCII 字母或数字，且至少有一个字母
+func looksLikeExt(ext string) bool {
+	ext = strings.TrimPrefix(ext, ".")
+	if ext == "" || len(ext) > maxGuessedExtLen {
+		return false
+	}
+	letter := false
+	for _, r := range ext {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+			letter = true
+		case r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return letter
+}
@@ -0,0 +1,33 @@
+package utils
+
+import "testing"
+
+func TestNewNameComposerCompose(t *testing.T) {
+	row := []string{"", "第一章", "7", "序言"}
+	tests := []struct {
+		template string
+		oldName  string
+		want     string
+	}{
+		{"{B}_{C:03}_{D}.{ext}", "a.mp3", "第一章_007_序言.mp3"},
+		{"{B}_{C:03}_{D}.{ext}", "README", "第一章_007_序言"},
+		{"{B}_{C:03}", "a.mp3", "第一章_007.mp3"},
+		{"{B}_{C:03}", "README", "第一章_007"},
+		{"{D}.txt", "a.mp3", "序言.txt"},
+		{"{D}.MP3", "a.mp3", "序言.MP3"},
+	}
+	for _, tt := range tests {
+		composer, err := ParseNewNameTemplate(tt.template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := composer.Compose(row, tt.oldName)
+		if err != nil {
+			t.Errorf("%s（原文件 %s）: %v", tt.template, tt.oldName, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s（原文件 %s）生成 %s，应为 %s", tt.template, tt.oldName, got, tt.want)
+		}
+	}
+}
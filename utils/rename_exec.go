@@ -0,0 +1,551 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RenameStatus 单行重命名的最终结果
+type RenameStatus int
+
+const (
+	RenameSucceeded RenameStatus = iota // 已重命名
+	RenameSkipped                       // 未执行，如无需修改或按设置跳过冲突
+	RenameFailed                        // 失败，如原文件不存在或执行出错
+	RenameAborted                       // 整批中止，未执行
+)
+
+var renameStatusNames = map[RenameStatus]string{
+	RenameSucceeded: "成功",
+	RenameSkipped:   "跳过",
+	RenameFailed:    "失败",
+	RenameAborted:   "已中止",
+}
+
+// String 返回结果的中文名称
+func (s RenameStatus) String() string {
+	if name, ok := renameStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知结果(%d)", int(s))
+}
+
+// ErrorKind 重命名失败或跳过的原因分类
+type ErrorKind int
+
+const (
+	KindNone             ErrorKind = iota // 无错误
+	KindNotFound                          // 原文件不存在
+	KindExists                            // 目标已存在
+	KindDuplicate                         // 表格中的文件名重复
+	KindUnsafe                            // 路径不安全
+	KindAmbiguous                         // 匹配到多个文件
+	KindInvalidName                       // 无法生成新文件名
+	KindInvalidOperation                  // 操作无效
+	KindPermission                        // 没有权限
+	KindCrossDevice                       // 跨磁盘或跨分区移动
+	KindBusy                              // 文件正被其他进程使用
+	KindOther                             // 其他错误
+)
+
+var errorKindNames = map[ErrorKind]string{
+	KindNone:             "",
+	KindNotFound:         "原文件不存在",
+	KindExists:           "目标已存在",
+	KindDuplicate:        "文件名重复",
+	KindUnsafe:           "路径不安全",
+	KindAmbiguous:        "匹配不唯一",
+	KindInvalidName:      "文件名无效",
+	KindInvalidOperation: "操作无效",
+	KindPermission:       "权限不足",
+	KindCrossDevice:      "跨设备移动",
+	KindBusy:             "文件被占用",
+	KindOther:            "其他错误",
+}
+
+// String 返回错误类型的中文名称
+func (k ErrorKind) String() string {
+	if name, ok := errorKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知类型(%d)", int(k))
+}
+
+var (
+	// ErrSourceMissing 原文件不存在
+	ErrSourceMissing = errors.New("文件不存在")
+	// ErrTargetExists 目标文件已存在
+	ErrTargetExists = errors.New("目标已存在")
+	// ErrUnsafePath 路径超出目标文件夹
+	ErrUnsafePath = errors.New("路径不安全")
+)
+
+// classifyRenameError 根据执行重命名时的错误判断错误类型
+func classifyRenameError(err error) ErrorKind {
+	switch {
+	case err == nil:
+		return KindNone
+	case errors.Is(err, ErrSourceMissing), errors.Is(err, fs.ErrNotExist):
+		return KindNotFound
+	case errors.Is(err, ErrTargetExists), errors.Is(err, fs.ErrExist):
+		return KindExists
+	case errors.Is(err, ErrUnsafePath):
+		return KindUnsafe
+	case errors.Is(err, fs.ErrPermission):
+		return KindPermission
+	case isCrossDevice(err):
+		return KindCrossDevice
+	case isBusy(err):
+		return KindBusy
+	}
+	return KindOther
+}
+
+// planErrorKind 返回计划中未执行的行对应的错误类型
+func planErrorKind(status PlanStatus) ErrorKind {
+	switch status {
+	case PlanSourceMissing:
+		return KindNotFound
+	case PlanTargetExists:
+		return KindExists
+	case PlanDuplicateTarget, PlanDuplicateSource:
+		return KindDuplicate
+	case PlanUnsafe:
+		return KindUnsafe
+	case PlanAmbiguous:
+		return KindAmbiguous
+	case PlanInvalidName, PlanIncompatibleName:
+		return KindInvalidName
+	case PlanInvalidOperation:
+		return KindInvalidOperation
+	}
+	return KindNone
+}
+
+// RenameResult 单行重命名的结果
+type RenameResult struct {
+	Row     int
+	Op      Operation
+	OldPath string
+	NewPath string // 成功时为文件最终所在路径，否则为计划中的新路径
+	Status  RenameStatus
+	Kind    ErrorKind
+	Detail  string
+	Match   string // 非完全一致的匹配说明，为空表示按原样找到了原文件
+	Err     error
+}
+
+// RenameFailures 把失败的行，以及因目标已存在或新文件名重复而被跳过的行汇总为一个错误，都没有时返回 nil
+func RenameFailures(results []RenameResult) error {
+	var failures []string
+	for _, result := range results {
+		switch {
+		case result.Status == RenameFailed:
+			failures = append(failures, fmt.Sprintf("第 %d 行 %s -> %s: %s", result.Row, result.OldPath, result.NewPath, result.Detail))
+		case result.Status == RenameSkipped && (result.Kind == KindExists || result.Kind == KindDuplicate):
+			failures = append(failures, fmt.Sprintf("第 %d 行 %s -> %s: 已跳过，%s", result.Row, result.OldPath, result.NewPath, result.Detail))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("重命名过程中有 %d 行失败或被跳过:\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// CountRenameResults 统计指定结果的行数
+func CountRenameResults(results []RenameResult, status RenameStatus) int {
+	count := 0
+	for _, result := range results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// renameStep 执行顺序中的一步
+type renameStep struct {
+	item   int  // 对应 plan.Items 的下标
+	toTemp bool // 为打破循环先把原文件移到临时文件名
+}
+
+// errMovedToTemp 表示循环重命名的最后一步失败，文件停留在临时文件名上
+var errMovedToTemp = errors.New("文件暂存在临时文件名")
+
+// renameOutcome 计划中一行的实际执行结果
+type renameOutcome struct {
+	item    int
+	newName string // 文件最终所在的文件名（相对目标文件夹）
+	trash   string // 删除时文件在回收站中的路径
+	note    string // 补充说明，如文件暂存在临时文件名
+	err     error
+}
+
+// orderRenameSteps 计算重命名、移动和删除的行之间的依赖顺序：目标文件名被另一行占用时，
+// 先执行占用它的那一行；若依赖首尾相接形成循环，则先把其中一行移到临时文件名
+func orderRenameSteps(plan *RenamePlan) []renameStep {
+	sources := make(map[string]int)
+	for i, item := range plan.Items {
+		if item.Runnable() && plan.vacates(item) {
+			sources[item.OldPath] = i
+		}
+	}
+	dependency := func(i int) (int, bool) {
+		j, ok := sources[plan.Items[i].NewPath]
+		return j, ok && j != i
+	}
+
+	var steps []renameStep
+	done := make([]bool, len(plan.Items))
+	walk := make([]int, len(plan.Items)) // 当前遍历路径中的位置+1，0 表示不在路径中
+	for start, item := range plan.Items {
+		if !item.Runnable() || !plan.vacates(item) || done[start] {
+			continue
+		}
+
+		// 沿依赖向下走，直到遇到没有依赖、依赖已完成或回到路径中的行
+		var path []int
+		cycleStart := -1
+		for i := start; ; {
+			path = append(path, i)
+			walk[i] = len(path)
+			j, ok := dependency(i)
+			if !ok || done[j] {
+				break
+			}
+			if walk[j] != 0 {
+				cycleStart = walk[j] - 1
+				break
+			}
+			i = j
+		}
+
+		if cycleStart < 0 {
+			for k := len(path) - 1; k >= 0; k-- {
+				steps = append(steps, renameStep{item: path[k]})
+			}
+		} else {
+			// 循环：先把循环起点移到临时文件名，腾出的文件名让循环逐个完成，
+			// 最后再把临时文件改为起点的目标文件名，然后处理进入循环之前的部分
+			steps = append(steps, renameStep{item: path[cycleStart], toTemp: true})
+			for k := len(path) - 1; k > cycleStart; k-- {
+				steps = append(steps, renameStep{item: path[k]})
+			}
+			for k := cycleStart; k >= 0; k-- {
+				steps = append(steps, renameStep{item: path[k]})
+			}
+		}
+
+		for _, i := range path {
+			done[i] = true
+			walk[i] = 0
+		}
+	}
+	return steps
+}
+
+// tempRenamePath 在原文件所在目录中生成一个未被占用的临时文件名
+func tempRenamePath(path string) string {
+	dir, base := filepath.Split(path)
+	for n := 0; ; n++ {
+		tmp := filepath.Join(dir, fmt.Sprintf(".%s.renametmp%d", base, n))
+		if _, err := os.Lstat(tmp); os.IsNotExist(err) {
+			return tmp
+		}
+	}
+}
+
+// orderPlanSteps 计算整个计划的执行顺序：先新建文件夹，再复制（复制的原文件可能随后被移走），
+// 最后按依赖顺序执行重命名、移动和删除
+func orderPlanSteps(plan *RenamePlan) []renameStep {
+	var steps []renameStep
+	for i, item := range plan.Items {
+		if item.Runnable() && item.Op == OpMkdir {
+			steps = append(steps, renameStep{item: i})
+		}
+	}
+	for i, item := range plan.Items {
+		if item.Runnable() && plan.copies(item) {
+			steps = append(steps, renameStep{item: i})
+		}
+	}
+	return append(steps, orderRenameSteps(plan)...)
+}
+
+// runRenamePlan 按依赖顺序执行计划中可执行的行，返回每行的执行结果；
+// 执行前总会再次确认目标未被占用，只有按设置覆盖的行才会覆盖已有文件，
+// 且不会覆盖其他行尚未移走的原文件
+func runRenamePlan(plan *RenamePlan, progressCallback ...ProgressCallback) []renameOutcome {
+	steps := orderPlanSteps(plan)
+	total := plan.RunnableCount()
+	tempPaths := make(map[int]string)
+	failed := make(map[int]error)
+	var outcomes []renameOutcome
+
+	// 尚未移走的原文件，覆盖模式下也不能覆盖这些文件
+	pending := make(map[string]bool)
+	for _, item := range plan.Items {
+		if item.Runnable() && item.OldPath != "" {
+			pending[item.OldPath] = true
+		}
+	}
+
+	// 执行前再次确认目标路径没有跑出目标文件夹（复制模式下为输出文件夹），防止预览后目录被换成符号链接
+	targetFolder := plan.TargetFolder()
+	resolvedRoot, err := resolveExisting(targetFolder)
+	if err != nil {
+		resolvedRoot = targetFolder
+	}
+
+	relative := func(path string) string {
+		if rel, err := filepath.Rel(plan.FolderPath, path); err == nil {
+			return rel
+		}
+		return path
+	}
+
+	for _, step := range steps {
+		item := plan.Items[step.item]
+
+		if step.toTemp {
+			tmp := tempRenamePath(item.OldPath)
+			if _, err := retryBusy(plan.Options.Retries, func() error { return os.Rename(item.OldPath, tmp) }); err != nil {
+				failed[step.item] = fmt.Errorf("移动到临时文件失败: %w", err)
+				continue
+			}
+			tempPaths[step.item] = tmp
+			delete(pending, item.OldPath)
+			continue
+		}
+
+		// 计算进度百分比
+		current := len(outcomes)
+		if len(progressCallback) > 0 && progressCallback[0] != nil {
+			progressCallback[0](current, total, float64(current)/float64(total)*100)
+		}
+
+		if err, ok := failed[step.item]; ok {
+			outcomes = append(outcomes, renameOutcome{item: step.item, newName: item.OldName, err: err})
+			continue
+		}
+
+		from := item.OldPath
+		if tmp, ok := tempPaths[step.item]; ok {
+			from = tmp
+		}
+
+		var err error
+		var moveNote, trashPath string
+		overwrite := false
+		retried := 0
+		if item.Op == OpMkdir {
+			err = makeFolder(item, targetFolder, resolvedRoot)
+		} else if _, statErr := os.Lstat(from); statErr != nil {
+			err = ErrSourceMissing
+		} else if item.Op == OpDelete {
+			retried, err = retryBusy(plan.Options.Retries, func() (err error) {
+				trashPath, err = moveToTrash(from)
+				return err
+			})
+			if err == nil {
+				moveNote = "已移到回收站: " + trashPath
+			}
+		} else if _, unsafeErr := checkPathInFolder(targetFolder, resolvedRoot, item.NewName); unsafeErr != nil {
+			err = fmt.Errorf("%w: 新文件名: %v", ErrUnsafePath, unsafeErr)
+		} else if targetOccupied(from, item.NewPath) {
+			if item.Status != PlanOverwrite || pending[item.NewPath] {
+				err = ErrTargetExists
+			}
+			overwrite = true
+		}
+		if err == nil && item.Op != OpMkdir && item.Op != OpDelete {
+			// 目标子文件夹不存在时自动创建
+			if err = os.MkdirAll(filepath.Dir(item.NewPath), 0755); err == nil {
+				// 文件被其他进程短暂占用（如共享文件夹、杀毒软件扫描）时等待后重试
+				retried, err = retryBusy(plan.Options.Retries, func() (err error) {
+					if plan.copies(item) {
+						moveNote, err = copyFile(from, item.NewPath, plan.Options.Copy, plan.CopyProgress)
+					} else {
+						moveNote, err = moveFile(from, item.NewPath, plan.CopyProgress)
+					}
+					return err
+				})
+			}
+		}
+		if err != nil && retried > 0 {
+			err = fmt.Errorf("%w（已重试 %d 次）", err, retried)
+		}
+		moveNote = joinNotes(retryNote(retried), moveNote)
+
+		switch {
+		case err == nil:
+			// 复制后原文件仍在原处，同样不能被其他行覆盖
+			if plan.vacates(item) {
+				delete(pending, item.OldPath)
+			}
+			outcome := renameOutcome{item: step.item, newName: item.NewName, trash: trashPath, note: moveNote}
+			if overwrite {
+				outcome.note = joinNotes("已覆盖原有的目标文件", moveNote)
+			}
+			outcomes = append(outcomes, outcome)
+		case from != item.OldPath:
+			// 文件已在临时文件名上，作为成功移动记录下来，以便撤销时找回
+			outcomes = append(outcomes, renameOutcome{
+				item:    step.item,
+				newName: relative(from),
+				note:    fmt.Sprintf("未能改为 %s（%v），文件暂存为 %s", item.NewName, err, filepath.Base(from)),
+				err:     errMovedToTemp,
+			})
+		default:
+			outcomes = append(outcomes, renameOutcome{item: step.item, newName: item.OldName, err: err})
+		}
+	}
+
+	// 完成时调用回调函数，进度为100%
+	if len(progressCallback) > 0 && progressCallback[0] != nil {
+		progressCallback[0](total, total, 100.0)
+	}
+	return outcomes
+}
+
+// makeFolder 执行新建文件夹的行，已存在同名文件夹时视为成功
+func makeFolder(item PlanItem, targetFolder, resolvedRoot string) error {
+	if _, err := checkPathInFolder(targetFolder, resolvedRoot, item.NewName); err != nil {
+		return fmt.Errorf("%w: 文件夹名: %v", ErrUnsafePath, err)
+	}
+	if info, err := os.Lstat(item.NewPath); err == nil && !info.IsDir() {
+		return ErrTargetExists
+	}
+	return os.MkdirAll(item.NewPath, 0755)
+}
+
+// ExecuteRenamePlan 按设置先备份，再执行已确认的重命名计划并写入重命名日志（复制模式不写日志），
+// 链式和循环重命名会按依赖顺序执行，按计划中的行序返回每一行的结果；
+// 单行失败只记录在结果中，返回的错误仅表示整批中止、备份失败或日志写入失败
+func ExecuteRenamePlan(plan *RenamePlan, progressCallback ...ProgressCallback) ([]RenameResult, error) {
+	results := planResults(plan)
+
+	// 整批中止：有冲突时不修改任何文件
+	if plan.Options.Collision == CollisionFail && plan.ConflictCount() > 0 {
+		abortRunnable(plan, results, "存在文件名冲突，整批中止")
+		return results, fmt.Errorf("有 %d 行存在文件名冲突，已按设置中止整批重命名，未修改任何文件", plan.ConflictCount())
+	}
+
+	// 执行前备份，备份失败时不修改任何文件
+	journal := newRenameJournal(plan.FolderPath)
+	if plan.Options.Backup != BackupNone {
+		archive, err := CreateRenameBackup(plan, journal.ID, plan.BackupProgress)
+		if err != nil {
+			abortRunnable(plan, results, "执行前备份失败，整批中止")
+			return results, fmt.Errorf("执行前备份失败，未修改任何文件: %v", err)
+		}
+		journal.Backup, plan.BackupPath = archive, archive
+	}
+	plan.journal = journal
+
+	for _, outcome := range runRenamePlan(plan, progressCallback...) {
+		item := plan.Items[outcome.item]
+		result := &results[outcome.item]
+		result.Detail = outcome.note
+		switch {
+		case outcome.err == errMovedToTemp:
+			result.Status = RenameFailed
+			result.Kind = KindOther
+			result.NewPath = filepath.Join(plan.FolderPath, outcome.newName)
+			result.Err = errors.New(outcome.note)
+			journal.record(item.Op, item.OldName, outcome.newName, "", nil)
+			journal.Entries[len(journal.Entries)-1].Result = outcome.note
+		case outcome.err != nil:
+			result.Status = RenameFailed
+			result.Kind = classifyRenameError(outcome.err)
+			result.Detail = outcome.err.Error()
+			result.Err = outcome.err
+			journal.record(item.Op, item.OldName, item.NewName, "", outcome.err)
+		default:
+			result.Status = RenameSucceeded
+			journal.record(item.Op, item.OldName, item.NewName, outcome.trash, nil)
+			if outcome.note != "" {
+				journal.Entries[len(journal.Entries)-1].Result = outcome.note
+			}
+		}
+	}
+
+	// 标出自动修正了文件名以及不是按原样找到原文件的行，方便核对
+	for i, item := range plan.Items {
+		if item.NameFix != "" {
+			results[i].Detail = joinNotes(results[i].Detail, "已修正文件名: "+item.NameFix)
+		}
+		results[i].Detail = joinNotes(results[i].Detail, results[i].Match)
+	}
+
+	// 未执行的行同样写入日志
+	for _, item := range plan.Items {
+		if !item.Runnable() && item.Status != PlanUnchanged {
+			journal.record(item.Op, item.OldName, item.NewName, "", errors.New(item.Status.String()))
+		}
+	}
+
+	// 记录本次重命名日志，供撤销使用；复制模式没有修改原文件，不需要撤销
+	if len(journal.Entries) > 0 && !plan.Options.CopyMode() {
+		if err := SaveRenameJournal(journal); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// planResults 按计划生成每一行执行前的结果：计划中无法执行的行为失败，其余暂为跳过
+func planResults(plan *RenamePlan) []RenameResult {
+	results := make([]RenameResult, len(plan.Items))
+	for i, item := range plan.Items {
+		results[i] = RenameResult{
+			Row:     item.Row,
+			Op:      item.Op,
+			OldPath: item.OldPath,
+			NewPath: item.NewPath,
+			Status:  RenameSkipped,
+			Kind:    planErrorKind(item.Status),
+			Detail:  item.Status.String(),
+			Match:   item.Match,
+		}
+		if item.Detail != "" {
+			results[i].Detail += ": " + item.Detail
+		}
+		switch {
+		case item.Status == PlanSourceMissing || item.Status == PlanDuplicateSource ||
+			item.Status == PlanUnsafe || item.Status == PlanAmbiguous || item.Status == PlanInvalidName ||
+			item.Status == PlanInvalidOperation || item.Status == PlanIncompatibleName:
+			results[i].Status = RenameFailed
+			results[i].Err = errors.New(results[i].Detail)
+		case item.Conflict() && plan.Options.Collision == CollisionFail:
+			results[i].Status = RenameFailed
+			results[i].Err = errors.New(results[i].Detail)
+		}
+	}
+	return results
+}
+
+// abortRunnable 把可执行的行标为整批中止
+func abortRunnable(plan *RenamePlan, results []RenameResult, detail string) {
+	for i, item := range plan.Items {
+		if item.Runnable() {
+			results[i].Status = RenameAborted
+			results[i].Detail = detail
+		}
+	}
+}
+
+// joinNotes 用分号连接非空的说明
+func joinNotes(notes ...string) string {
+	var parts []string
+	for _, note := range notes {
+		if note != "" {
+			parts = append(parts, note)
+		}
+	}
+	return strings.Join(parts, "；")
+}
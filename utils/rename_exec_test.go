@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testFolder 测试中使用的初始文件，内容与文件名相同，方便核对文件最终的去向
+var testFolder = map[string]string{"a": "a", "b": "b", "c": "c", "d": "d", "e": "e"}
+
+// runTestPlan 在新建的临时文件夹中生成并执行计划，返回计划、结果和文件夹
+func runTestPlan(t *testing.T, files map[string]string, rows []ExcelData, options RenameOptions) (*RenamePlan, []RenameResult, string) {
+	t.Helper()
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, files)
+	for i := range rows {
+		rows[i].Row = i + 2
+	}
+	plan, err := PlanRename(dir, rows, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := ExecuteRenamePlan(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plan, results, dir
+}
+
+// resultStatuses 按行序返回每一行的结果
+func resultStatuses(results []RenameResult) []RenameStatus {
+	statuses := make([]RenameStatus, len(results))
+	for i, result := range results {
+		statuses[i] = result.Status
+	}
+	return statuses
+}
+
+// checkStatuses 检查每一行的结果
+func checkStatuses(t *testing.T, results []RenameResult, want ...RenameStatus) {
+	t.Helper()
+	got := resultStatuses(results)
+	if len(got) != len(want) {
+		t.Fatalf("结果为 %v，应为 %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("结果为 %v，应为 %v（第 %d 行: %s）", got, want, results[i].Row, results[i].Detail)
+		}
+	}
+}
+
+func TestOverwriteDuplicateTargetsKeepTableOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		rows   []ExcelData
+		status []RenameStatus
+		files  map[string]string
+	}{
+		{
+			// 互不依赖的重复目标按表格顺序执行，后一行覆盖前一行
+			name:   "互不依赖",
+			rows:   []ExcelData{{OldName: "a", NewName: "T"}, {OldName: "c", NewName: "T"}},
+			status: []RenameStatus{RenameSucceeded, RenameSucceeded},
+			files:  map[string]string{"T": "c", "b": "b", "d": "d", "e": "e"},
+		},
+		{
+			// 第 1 行在循环中，后一行在循环之后覆盖
+			name:   "第一行在循环中",
+			rows:   []ExcelData{{OldName: "a", NewName: "b"}, {OldName: "b", NewName: "a"}, {OldName: "c", NewName: "b"}},
+			status: []RenameStatus{RenameSucceeded, RenameSucceeded, RenameSucceeded},
+			files:  map[string]string{"a": "b", "b": "c", "d": "d", "e": "e"},
+		},
+		{
+			// 后一行的原文件名要被另一行使用，会随该行提前执行，因此不执行，等待它的行也不覆盖其原文件
+			name:   "后一行被其他行等待",
+			rows:   []ExcelData{{OldName: "e", NewName: "c"}, {OldName: "a", NewName: "T"}, {OldName: "c", NewName: "T"}},
+			status: []RenameStatus{RenameSkipped, RenameSucceeded, RenameSkipped},
+			files:  map[string]string{"T": "a", "b": "b", "c": "c", "d": "d", "e": "e"},
+		},
+		{
+			// 后一行属于循环，且第 1 行排在循环之前：整条链都不执行，不丢失任何文件
+			name:   "后一行在循环中",
+			rows:   []ExcelData{{OldName: "e", NewName: "c"}, {OldName: "c", NewName: "a"}, {OldName: "a", NewName: "b"}, {OldName: "b", NewName: "a"}},
+			status: []RenameStatus{RenameSkipped, RenameSkipped, RenameSkipped, RenameSkipped},
+			files:  testFolder,
+		},
+		{
+			// 复制在重命名之前执行，作为后一行时不执行
+			name:   "后一行为复制",
+			rows:   []ExcelData{{OldName: "a", NewName: "T"}, {OldName: "c", NewName: "T", Operation: "复制"}},
+			status: []RenameStatus{RenameSucceeded, RenameSkipped},
+			files:  map[string]string{"T": "a", "b": "b", "c": "c", "d": "d", "e": "e"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, results, dir := runTestPlan(t, testFolder, tt.rows, RenameOptions{Collision: CollisionOverwrite})
+			checkStatuses(t, results, tt.status...)
+			checkTestFiles(t, dir, tt.files)
+		})
+	}
+}
+
+func TestRenameCyclesAndChains(t *testing.T) {
+	tests := []struct {
+		name  string
+		rows  []ExcelData
+		files map[string]string
+	}{
+		{
+			name:  "交换",
+			rows:  []ExcelData{{OldName: "a", NewName: "b"}, {OldName: "b", NewName: "a"}},
+			files: map[string]string{"a": "b", "b": "a", "c": "c", "d": "d", "e": "e"},
+		},
+		{
+			name:  "三个文件的循环",
+			rows:  []ExcelData{{OldName: "a", NewName: "b"}, {OldName: "b", NewName: "c"}, {OldName: "c", NewName: "a"}},
+			files: map[string]string{"a": "c", "b": "a", "c": "b", "d": "d", "e": "e"},
+		},
+		{
+			// 按表格顺序执行时 a 会覆盖 b，需从链尾开始执行
+			name:  "链式",
+			rows:  []ExcelData{{OldName: "a", NewName: "b"}, {OldName: "b", NewName: "c"}, {OldName: "c", NewName: "f"}},
+			files: map[string]string{"b": "a", "c": "b", "f": "c", "d": "d", "e": "e"},
+		},
+		{
+			name: "循环与链式混合",
+			rows: []ExcelData{
+				{OldName: "a", NewName: "b"}, {OldName: "d", NewName: "g"}, {OldName: "b", NewName: "a"},
+				{OldName: "e", NewName: "d"}, {OldName: "c", NewName: "e"},
+			},
+			files: map[string]string{"a": "b", "b": "a", "d": "e", "e": "c", "g": "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, results, dir := runTestPlan(t, testFolder, tt.rows, RenameOptions{})
+			for _, result := range results {
+				if result.Status != RenameSucceeded {
+					t.Fatalf("第 %d 行%s: %s", result.Row, result.Status, result.Detail)
+				}
+			}
+			// 不应留下临时文件，也不应丢失或多出任何文件
+			checkTestFiles(t, dir, tt.files)
+		})
+	}
+}
+
+func TestOrderRenameStepsCycleWithTail(t *testing.T) {
+	// 第 1 行等待循环中的行腾出目标：先把循环起点移到临时文件名，完成循环后再执行第 1 行
+	dir := t.TempDir()
+	item := func(row int, oldName, newName string) PlanItem {
+		return PlanItem{Row: row, OldName: oldName, NewName: newName, OldPath: filepath.Join(dir, oldName), NewPath: filepath.Join(dir, newName), Status: PlanChain}
+	}
+	plan := &RenamePlan{FolderPath: dir, Items: []PlanItem{
+		item(2, "x", "a"),
+		item(3, "a", "b"),
+		item(4, "b", "a"),
+		item(5, "y", "x"),
+	}}
+	got := orderRenameSteps(plan)
+	want := []renameStep{{item: 1, toTemp: true}, {item: 2}, {item: 1}, {item: 0}, {item: 3}}
+	if len(got) != len(want) {
+		t.Fatalf("执行顺序为 %v，应为 %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("执行顺序为 %v，应为 %v", got, want)
+		}
+	}
+}
+
+func TestCycleFailureLeavesFileAtTempName(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"a": "a", "d/x": "x"})
+	plan, err := PlanRename(dir, []ExcelData{{Row: 2, OldName: "a", NewName: "d/x"}, {Row: 3, OldName: "d/x", NewName: "a"}}, RenameOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.RunnableCount() != 2 {
+		t.Fatal(plan.Summary())
+	}
+
+	// 预览后 d 被换成指向文件夹外的符号链接：循环最后一步的目标不再安全，执行时必须拒绝
+	outside := t.TempDir()
+	writeTestFiles(t, outside, map[string]string{"x": "x"})
+	if err := os.RemoveAll(filepath.Join(dir, "d")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "d")); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+
+	results, err := ExecuteRenamePlan(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := results[0]
+	if failed.Status != RenameFailed || !strings.Contains(failed.Detail, "暂存") {
+		t.Fatalf("第 2 行应报告文件暂存在临时文件名: %s %s", failed.Status, failed.Detail)
+	}
+	content, err := os.ReadFile(failed.NewPath)
+	if err != nil || string(content) != "a" {
+		t.Fatalf("原文件应保留在临时文件名 %s: %q, %v", failed.NewPath, content, err)
+	}
+	if filepath.Dir(failed.NewPath) != dir || !strings.Contains(filepath.Base(failed.NewPath), ".renametmp") {
+		t.Fatalf("临时文件名不正确: %s", failed.NewPath)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "a")); err != nil || string(content) != "x" {
+		t.Fatalf("循环中已完成的一步应保留: %q, %v", content, err)
+	}
+
+	// 日志按成功移动记录临时文件名，以便撤销时找回
+	journal, err := LastRenameJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded bool
+	for _, entry := range journal.Entries {
+		if entry.OldName == "a" && entry.Success && entry.NewName == filepath.Base(failed.NewPath) {
+			recorded = true
+		}
+	}
+	if !recorded {
+		t.Fatalf("日志中没有记录暂存的临时文件名: %+v", journal.Entries)
+	}
+}
+
+func TestRenameFilesReportsSkippedConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    []ExcelData
+		wantErr bool
+	}{
+		{"目标已存在", []ExcelData{{OldName: "a", NewName: "b"}}, true},
+		{"新文件名重复", []ExcelData{{OldName: "a", NewName: "x"}, {OldName: "c", NewName: "x"}}, true},
+		{"无需修改", []ExcelData{{OldName: "a", NewName: "a"}}, false},
+		{"全部成功", []ExcelData{{OldName: "a", NewName: "x"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempConfig(t)
+			dir := t.TempDir()
+			writeTestFiles(t, dir, testFolder)
+			err := RenameFiles(dir, tt.rows)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenameFiles 返回 %v，是否应返回错误: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCopyModeLeavesOriginals(t *testing.T) {
+	files := map[string]string{"a.txt": "A", "b.txt": "B"}
+	rows := []ExcelData{
+		{Row: 2, OldName: "a.txt", NewName: "x/1.txt"},
+		{Row: 3, OldName: "a.txt", NewName: "2.txt"},
+		{Row: 4, OldName: "b.txt", NewName: "a.txt"},
+	}
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, files)
+
+	for _, method := range CopyMethods {
+		out := filepath.Join(t.TempDir(), "out")
+		plan, err := PlanRename(dir, rows, RenameOptions{Output: out, Copy: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := ExecuteRenamePlan(plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkStatuses(t, results, RenameSucceeded, RenameSucceeded, RenameSucceeded)
+		checkTestFiles(t, out, map[string]string{"x/1.txt": "A", "2.txt": "A", "a.txt": "B"})
+		checkTestFiles(t, dir, files)
+
+		// 再次复制到同一文件夹时目标已存在，按冲突处理方式追加序号；
+		// 硬链接的副本与原文件是同一个文件，不算冲突
+		plan, err = PlanRename(dir, rows, RenameOptions{Output: out, Copy: method, Collision: CollisionSuffix})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := PlanAutoSuffix
+		if method == CopyHardLink {
+			want = PlanOK
+		}
+		if item := plan.Items[1]; item.Status != want {
+			t.Errorf("%s: 再次复制时计划为 %+v，状态应为 %s", method, item, want)
+		} else if want == PlanAutoSuffix && item.NewName != "2 (1).txt" {
+			t.Errorf("%s: 再次复制时新文件名为 %s，应为 2 (1).txt", method, item.NewName)
+		}
+	}
+
+	if _, err := PlanRename(dir, rows, RenameOptions{Output: dir}); err == nil {
+		t.Error("输出文件夹与原文件夹相同时应报错")
+	}
+	// 复制模式不改动原文件，不写入重命名历史
+	if journals, err := ListRenameJournals(); err != nil || len(journals) != 0 {
+		t.Errorf("重命名历史有 %d 条（%v），应为空", len(journals), err)
+	}
+}
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FolderRenameResult 多文件夹重命名中一个文件夹的结果。每个文件夹全部成功或不做任何修改：
+// 计划中有无法执行的行时不执行该文件夹，执行中有行失败时撤销该文件夹中已完成的修改
+type FolderRenameResult struct {
+	Folder     string
+	Results    []RenameResult
+	Succeeded  int   // 成功的行数，回滚后为 0
+	RolledBack bool  // 执行中有行失败，已撤销该文件夹中已完成的修改
+	Err        error // 未执行、执行失败或回滚失败的原因，为 nil 表示该文件夹全部成功
+}
+
+// Summary 返回该文件夹结果的中文说明
+func (r FolderRenameResult) Summary() string {
+	switch {
+	case r.Err == nil:
+		return fmt.Sprintf("成功 %d 行", r.Succeeded)
+	case r.RolledBack:
+		return "已回滚: " + r.Err.Error()
+	}
+	return "未修改: " + r.Err.Error()
+}
+
+// ExpandRenameFolders 把文件夹列表展开为要处理的文件夹，含 * ? [ 的项按通配符匹配（如 release/*），
+// 只保留匹配到的非隐藏文件夹；结果去重，通配符匹配到的文件夹按自然顺序排列
+func ExpandRenameFolders(patterns []string) ([]string, error) {
+	var folders []string
+	seen := make(map[string]bool)
+	add := func(folder string) {
+		if key := filepath.Clean(folder); !seen[key] {
+			seen[key] = true
+			folders = append(folders, key)
+		}
+	}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if !strings.ContainsAny(pattern, "*?[") {
+			info, err := os.Stat(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("读取文件夹失败: %v", err)
+			}
+			if !info.IsDir() {
+				return nil, fmt.Errorf("不是文件夹: %s", pattern)
+			}
+			add(pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("文件夹通配符无效: %s: %v", pattern, err)
+		}
+		SortNatural(matches)
+		found := false
+		for _, match := range matches {
+			if strings.HasPrefix(filepath.Base(match), ".") {
+				continue
+			}
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				add(match)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("没有与 %s 匹配的文件夹", pattern)
+		}
+	}
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("请至少选择一个文件夹")
+	}
+	return folders, nil
+}
+
+// PlanRenameFolders 为每个文件夹分别生成同一份映射的重命名计划。
+// 复制模式下每个文件夹的副本写入输出文件夹中与其同名的子文件夹，如 zh/ 的副本写入 输出/zh/
+func PlanRenameFolders(folders []string, renameData []ExcelData, options RenameOptions) ([]*RenamePlan, error) {
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("请至少选择一个文件夹")
+	}
+	outputs := make(map[string]string)
+	plans := make([]*RenamePlan, len(folders))
+	for i, folder := range folders {
+		folderOptions := options
+		if options.CopyMode() && len(folders) > 1 {
+			name := filepath.Base(filepath.Clean(folder))
+			if other, ok := outputs[name]; ok {
+				return nil, fmt.Errorf("复制模式下 %s 与 %s 同名，副本会写入同一个输出文件夹", other, folder)
+			}
+			outputs[name] = folder
+			folderOptions.Output = filepath.Join(options.Output, name)
+		}
+		plan, err := PlanRename(folder, renameData, folderOptions)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", folder, err)
+		}
+		plans[i] = plan
+	}
+	return plans, nil
+}
+
+// blockingCount 统计计划中无法执行、会导致该文件夹只改一部分的行数，无需修改的行不算在内
+func blockingCount(plan *RenamePlan) int {
+	count := 0
+	for _, item := range plan.Items {
+		if !item.Runnable() && item.Status != PlanUnchanged {
+			count++
+		}
+	}
+	return count
+}
+
+// ExecuteFolderPlans 依次执行各文件夹的计划，每个文件夹全部成功或不做任何修改，一个文件夹失败不影响其他文件夹。
+// 进度按已处理的文件夹数报告
+func ExecuteFolderPlans(plans []*RenamePlan, progressCallback ...ProgressCallback) []FolderRenameResult {
+	report := func(current int) {
+		if len(progressCallback) > 0 && progressCallback[0] != nil {
+			progressCallback[0](current, len(plans), float64(current)/float64(len(plans))*100)
+		}
+	}
+
+	folderResults := make([]FolderRenameResult, len(plans))
+	for i, plan := range plans {
+		report(i)
+		folderResults[i] = executeFolderPlan(plan)
+	}
+	report(len(plans))
+	return folderResults
+}
+
+// executeFolderPlan 执行一个文件夹的计划，有行失败时回滚
+func executeFolderPlan(plan *RenamePlan) FolderRenameResult {
+	result := FolderRenameResult{Folder: plan.FolderPath}
+	if blocked := blockingCount(plan); blocked > 0 {
+		result.Results = planResults(plan)
+		abortRunnable(plan, result.Results, "该文件夹有行无法执行，整个文件夹未修改")
+		result.Err = fmt.Errorf("有 %d 行无法执行，整个文件夹未修改", blocked)
+		return result
+	}
+
+	// 复制模式下记下输出文件夹是否已存在，回滚时删除本次新建的输出文件夹
+	_, statErr := os.Stat(plan.TargetFolder())
+	createdOutput := plan.Options.CopyMode() && os.IsNotExist(statErr)
+
+	results, err := ExecuteRenamePlan(plan)
+	result.Results = results
+	result.Succeeded = CountRenameResults(results, RenameSucceeded)
+	failed := CountRenameResults(results, RenameFailed)
+	if err != nil && plan.journal == nil {
+		// 备份失败或整批中止，没有修改任何文件
+		result.Err = err
+		return result
+	}
+	if failed == 0 && err == nil {
+		return result
+	}
+
+	// 有行失败（或日志写入失败，无法再撤销），撤销该文件夹中已完成的修改
+	reason := err
+	if failed > 0 {
+		reason = fmt.Errorf("有 %d 行执行失败", failed)
+	}
+	if result.Succeeded == 0 {
+		if createdOutput {
+			os.Remove(plan.TargetFolder())
+		}
+		result.Err = fmt.Errorf("%v，没有修改任何文件", reason)
+		return result
+	}
+	if rollbackErr := rollbackFolderPlan(plan, results, createdOutput); rollbackErr != nil {
+		result.Err = fmt.Errorf("%v，回滚失败: %v", reason, rollbackErr)
+		return result
+	}
+	for i := range result.Results {
+		if result.Results[i].Status == RenameSucceeded {
+			result.Results[i].Status = RenameAborted
+			result.Results[i].Detail = joinNotes(result.Results[i].Detail, "该文件夹有行失败，已撤销")
+		}
+	}
+	result.Succeeded = 0
+	result.RolledBack = true
+	result.Err = fmt.Errorf("%v，已撤销该文件夹中的 %d 行修改", reason, CountRenameResults(result.Results, RenameAborted))
+	return result
+}
+
+// rollbackFolderPlan 撤销一个文件夹中已完成的修改：复制模式下删除写入的副本，否则按重命名日志撤销。
+// createdOutput 表示输出文件夹是本次执行时新建的，删除副本后连同其中留下的空文件夹一起删除
+func rollbackFolderPlan(plan *RenamePlan, results []RenameResult, createdOutput bool) error {
+	if !plan.Options.CopyMode() {
+		return UndoRename(plan.journal)
+	}
+	var failures []string
+	output := filepath.Clean(plan.TargetFolder())
+	for i := len(results) - 1; i >= 0; i-- {
+		if results[i].Status == RenameSucceeded && plan.copies(plan.Items[i]) {
+			if err := os.Remove(results[i].NewPath); err != nil && !os.IsNotExist(err) {
+				failures = append(failures, fmt.Sprintf("删除副本失败: %v", err))
+				continue
+			}
+			if createdOutput {
+				// 副本所在的子文件夹同样是本次新建的，变空后删除；仍有其他文件时 Remove 会失败并停下
+				for dir := filepath.Dir(results[i].NewPath); strings.HasPrefix(dir, output+string(filepath.Separator)); dir = filepath.Dir(dir) {
+					if os.Remove(dir) != nil {
+						break
+					}
+				}
+			}
+		}
+	}
+	if createdOutput {
+		if err := os.Remove(output); err != nil && !os.IsNotExist(err) {
+			failures = append(failures, fmt.Sprintf("删除输出文件夹失败: %v", err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// RenameFilesInFolders 把同一份映射应用到多个文件夹：展开 folders 中的通配符（如 release/*），
+// 为每个文件夹生成计划后依次执行，每个文件夹全部成功或不做任何修改，返回每个文件夹的结果。
+// confirm 不为 nil 时先把各文件夹的计划交给它确认，返回 false 时不执行任何文件夹，结果为 nil
+func RenameFilesInFolders(folders []string, renameData []ExcelData, options RenameOptions, confirm func([]*RenamePlan) bool, progressCallback ...ProgressCallback) ([]FolderRenameResult, error) {
+	expanded, err := ExpandRenameFolders(folders)
+	if err != nil {
+		return nil, err
+	}
+	plans, err := PlanRenameFolders(expanded, renameData, options)
+	if err != nil {
+		return nil, err
+	}
+	if confirm != nil && !confirm(plans) {
+		return nil, nil
+	}
+	return ExecuteFolderPlans(plans, progressCallback...), nil
+}
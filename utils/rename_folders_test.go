@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestRelease 建立 zh、en、sw 三个文件相同的文件夹，返回它们的父文件夹
+func writeTestRelease(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, lang := range []string{"zh", "en", "sw"} {
+		writeTestFiles(t, filepath.Join(root, lang), map[string]string{"a": "a", "b": "b"})
+	}
+	return root
+}
+
+func TestRenameFilesInFoldersRollsBackOnlyFailedFolder(t *testing.T) {
+	useTempConfig(t)
+	root := writeTestRelease(t)
+	rows := []ExcelData{{Row: 2, OldName: "a", NewName: "x"}, {Row: 3, OldName: "b", NewName: "y"}}
+
+	folderResults, err := RenameFilesInFolders([]string{filepath.Join(root, "*")}, rows, RenameOptions{}, func(plans []*RenamePlan) bool {
+		if len(plans) != 3 {
+			t.Fatalf("展开为 %d 个文件夹，应为 3 个", len(plans))
+		}
+		// 计划生成后 zh 中出现了与第 3 行目标同名的文件，执行到该行时失败
+		writeTestFiles(t, filepath.Join(root, "zh"), map[string]string{"y": "late"})
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, result := range folderResults {
+		lang := filepath.Base(result.Folder)
+		if lang == "zh" {
+			if result.Err == nil || !result.RolledBack {
+				t.Errorf("zh 应已回滚: %s", result.Summary())
+			}
+			checkTestFiles(t, result.Folder, map[string]string{"a": "a", "b": "b", "y": "late"})
+			continue
+		}
+		if result.Err != nil {
+			t.Errorf("%s 应全部成功: %s", lang, result.Summary())
+		}
+		checkTestFiles(t, result.Folder, map[string]string{"x": "a", "y": "b"})
+	}
+}
+
+func TestRenameFilesInFoldersCanBeCancelled(t *testing.T) {
+	useTempConfig(t)
+	root := writeTestRelease(t)
+	rows := []ExcelData{{Row: 2, OldName: "a", NewName: "x"}}
+
+	folderResults, err := RenameFilesInFolders([]string{filepath.Join(root, "*")}, rows, RenameOptions{}, func([]*RenamePlan) bool { return false })
+	if err != nil || folderResults != nil {
+		t.Fatalf("取消后返回 %v, %v", folderResults, err)
+	}
+	checkTestFiles(t, filepath.Join(root, "en"), map[string]string{"a": "a", "b": "b"})
+}
+
+func TestCopyModeRollbackRemovesOutputFolder(t *testing.T) {
+	useTempConfig(t)
+	root := writeTestRelease(t)
+	output := t.TempDir()
+	rows := []ExcelData{{Row: 2, OldName: "a", NewName: "sub/x"}, {Row: 3, OldName: "b", NewName: "y"}}
+	folders := []string{filepath.Join(root, "zh"), filepath.Join(root, "en")}
+
+	folderResults, err := RenameFilesInFolders(folders, rows, RenameOptions{Output: output}, func([]*RenamePlan) bool {
+		// 计划生成后 zh 中的 b 被删除，复制第 3 行时失败
+		if err := os.Remove(filepath.Join(root, "zh", "b")); err != nil {
+			t.Fatal(err)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !folderResults[0].RolledBack {
+		t.Fatalf("zh 应已回滚: %s", folderResults[0].Summary())
+	}
+	if _, err := os.Lstat(filepath.Join(output, "zh")); !os.IsNotExist(err) {
+		t.Errorf("回滚后应删除本次新建的输出文件夹 zh: %v", err)
+	}
+	if folderResults[1].Err != nil {
+		t.Fatalf("en 应全部成功: %s", folderResults[1].Summary())
+	}
+	checkTestFiles(t, filepath.Join(output, "en"), map[string]string{"sub/x": "a", "y": "b"})
+}
@@ -0,0 +1,290 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JournalEntry 重命名日志中的一行记录
+type JournalEntry struct {
+	Op        Operation `json:"op,omitempty"` // 该行的操作，旧日志中没有该字段，均为重命名
+	OldName   string    `json:"old_name"`
+	NewName   string    `json:"new_name"`
+	TrashPath string    `json:"trash_path,omitempty"` // 删除时文件在回收站中的路径
+	Success   bool      `json:"success"`
+	Result    string    `json:"result"`           // 执行结果说明
+	Size      int64     `json:"size,omitempty"`   // 执行后的文件大小，用于撤销前校验
+	ModTime   time.Time `json:"mod_time"`         // 执行后的修改时间，用于撤销前校验
+	Undone    bool      `json:"undone,omitempty"` // 撤销时已恢复该行，部分撤销失败后再次撤销时跳过
+}
+
+// path 返回该行执行后文件所在的位置：删除的文件在回收站中，其他为新文件名
+func (e JournalEntry) path(folder string) string {
+	if e.Op == OpDelete {
+		return e.TrashPath
+	}
+	return filepath.Join(folder, e.NewName)
+}
+
+// RenameJournal 一次批量重命名的日志
+type RenameJournal struct {
+	ID       string         `json:"id"`
+	Time     time.Time      `json:"time"`
+	Folder   string         `json:"folder"`
+	Entries  []JournalEntry `json:"entries"`
+	Backup   string         `json:"backup,omitempty"` // 执行前生成的备份压缩包
+	UndoneAt *time.Time     `json:"undone_at,omitempty"`
+}
+
+// SuccessCount 统计成功重命名的行数
+func (j *RenameJournal) SuccessCount() int {
+	count := 0
+	for _, entry := range j.Entries {
+		if entry.Success {
+			count++
+		}
+	}
+	return count
+}
+
+// Undone 判断该次重命名是否已被撤销
+func (j *RenameJournal) Undone() bool {
+	return j.UndoneAt != nil
+}
+
+// newRenameJournal 创建一份新的重命名日志
+func newRenameJournal(folderPath string) *RenameJournal {
+	now := time.Now()
+	return &RenameJournal{
+		ID:     now.Format("20060102-150405.000000"),
+		Time:   now,
+		Folder: folderPath,
+	}
+}
+
+// record 记录一行的执行结果，成功时同时记录执行后文件的大小和修改时间
+func (j *RenameJournal) record(op Operation, oldName, newName, trashPath string, err error) {
+	entry := JournalEntry{Op: op, OldName: oldName, NewName: newName, TrashPath: trashPath, Success: err == nil, Result: "成功"}
+	if err != nil {
+		entry.Result = err.Error()
+	} else if info, statErr := os.Lstat(entry.path(j.Folder)); statErr == nil {
+		entry.Size = info.Size()
+		entry.ModTime = info.ModTime()
+	}
+	j.Entries = append(j.Entries, entry)
+}
+
+// renameHistoryDir 返回重命名历史的存放目录
+func renameHistoryDir() (string, error) {
+	dir, err := appConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rename_history"), nil
+}
+
+// SaveRenameJournal 将重命名日志写入本地历史记录
+func SaveRenameJournal(journal *RenameJournal) error {
+	dir, err := renameHistoryDir()
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化重命名日志失败: %v", err)
+	}
+	return writeConfigFile(filepath.Join(dir, journal.ID+".json"), content)
+}
+
+// ListRenameJournals 读取所有重命名历史，按时间从新到旧排列
+func ListRenameJournals() ([]*RenameJournal, error) {
+	dir, err := renameHistoryDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("读取历史记录失败: %v", err)
+	}
+
+	var journals []*RenameJournal
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取重命名日志失败: %v", err)
+		}
+		var journal RenameJournal
+		if err := json.Unmarshal(content, &journal); err != nil {
+			return nil, fmt.Errorf("解析重命名日志 %s 失败: %v", filepath.Base(file), err)
+		}
+		journals = append(journals, &journal)
+	}
+
+	sort.Slice(journals, func(a, b int) bool {
+		return journals[a].Time.After(journals[b].Time)
+	})
+	return journals, nil
+}
+
+// LastRenameJournal 返回最近一次尚未撤销且有成功记录的重命名日志
+func LastRenameJournal() (*RenameJournal, error) {
+	journals, err := ListRenameJournals()
+	if err != nil {
+		return nil, err
+	}
+	for _, journal := range journals {
+		if !journal.Undone() && journal.SuccessCount() > 0 {
+			return journal, nil
+		}
+	}
+	return nil, fmt.Errorf("没有可撤销的重命名记录")
+}
+
+// pending 判断该行是否成功执行且尚未被撤销
+func (e JournalEntry) pending() bool {
+	return e.Success && !e.Undone
+}
+
+// movesFile 判断该行是否把文件从原文件名移走，撤销时需要移回
+func (e JournalEntry) movesFile() bool {
+	return e.Op == OpRename || e.Op == OpMove
+}
+
+// CheckUndo 检查撤销条件：重命名、移动或复制后的文件以及回收站中的文件仍然存在且未被修改，
+// 要恢复的原文件名未被占用；新建的文件夹撤销时只删除空文件夹，不需要检查
+func CheckUndo(journal *RenameJournal) []string {
+	var problems []string
+	if journal.Undone() {
+		return append(problems, "该次重命名已经撤销")
+	}
+
+	// 本次被重命名或移动到的名称，撤销时会先被腾出，不算占用
+	renamedTo := make(map[string]bool)
+	for _, entry := range journal.Entries {
+		if entry.pending() && entry.movesFile() {
+			renamedTo[entry.NewName] = true
+		}
+	}
+
+	for _, entry := range journal.Entries {
+		if !entry.pending() || entry.Op == OpMkdir {
+			continue
+		}
+		newPath := entry.path(journal.Folder)
+		oldPath := filepath.Join(journal.Folder, entry.OldName)
+
+		info, err := os.Lstat(newPath)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("文件已不存在: %s", newPath))
+			continue
+		}
+		if info.Size() != entry.Size || !info.ModTime().Equal(entry.ModTime) {
+			problems = append(problems, fmt.Sprintf("文件已被修改: %s", newPath))
+		}
+		if entry.Op != OpCopy && !renamedTo[entry.OldName] && targetOccupied(newPath, oldPath) {
+			problems = append(problems, fmt.Sprintf("原文件名已被占用: %s", oldPath))
+		}
+	}
+	return problems
+}
+
+// UndoRename 撤销一次批量重命名，撤销前先整体校验，任何一个文件不满足条件都不会执行
+func UndoRename(journal *RenameJournal, progressCallback ...ProgressCallback) error {
+	if problems := CheckUndo(journal); len(problems) > 0 {
+		return fmt.Errorf("无法撤销，有 %d 个问题:\n%s", len(problems), strings.Join(problems, "\n"))
+	}
+
+	// 把成功重命名和移动的行反向组成新的计划，交换和循环重命名同样按依赖顺序恢复
+	var reverse []ExcelData
+	for i, entry := range journal.Entries {
+		if entry.pending() && entry.movesFile() {
+			reverse = append(reverse, ExcelData{Row: i + 1, OldName: entry.NewName, NewName: entry.OldName})
+		}
+	}
+	plan, err := PlanRename(journal.Folder, reverse, RenameOptions{})
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+	for _, item := range plan.Items {
+		if !item.Runnable() && item.Status != PlanUnchanged {
+			failures = append(failures, fmt.Sprintf("无法恢复 %s -> %s: %s", item.OldPath, item.NewPath, item.Status))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("无法撤销，有 %d 个问题:\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+
+	for _, outcome := range runRenamePlan(plan, progressCallback...) {
+		item := plan.Items[outcome.item]
+		switch {
+		case outcome.err == errMovedToTemp:
+			failures = append(failures, fmt.Sprintf("撤销失败 %s: %s", item.OldPath, outcome.note))
+		case outcome.err != nil:
+			failures = append(failures, fmt.Sprintf("撤销失败 %s -> %s: %v", item.OldPath, item.NewPath, outcome.err))
+		default:
+			journal.Entries[item.Row-1].Undone = true
+		}
+	}
+	failures = append(failures, undoOtherOperations(journal)...)
+
+	// 有行未能恢复时不标记为已撤销，处理后可再次撤销剩下的行
+	if len(failures) == 0 {
+		now := time.Now()
+		journal.UndoneAt = &now
+	}
+	if err := SaveRenameJournal(journal); err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("撤销过程中有 %d 个错误:\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// undoOtherOperations 撤销重命名和移动以外的行：删除复制出的文件，从回收站恢复删除的文件，
+// 最后按与新建相反的顺序删除新建的文件夹，文件夹中已有其他文件时保留并报告；已恢复的行标记为已撤销
+func undoOtherOperations(journal *RenameJournal) []string {
+	var failures []string
+	for i := range journal.Entries {
+		entry := &journal.Entries[i]
+		if !entry.pending() {
+			continue
+		}
+		switch entry.Op {
+		case OpCopy:
+			path := entry.path(journal.Folder)
+			if info, err := os.Lstat(path); err == nil && (info.Size() != entry.Size || !info.ModTime().Equal(entry.ModTime)) {
+				failures = append(failures, fmt.Sprintf("复制的文件已被修改，未删除: %s", path))
+			} else if err := os.Remove(path); err != nil {
+				failures = append(failures, fmt.Sprintf("删除复制的文件失败: %v", err))
+			} else {
+				entry.Undone = true
+			}
+		case OpDelete:
+			if err := restoreFromTrash(entry.TrashPath, filepath.Join(journal.Folder, entry.OldName)); err != nil {
+				failures = append(failures, fmt.Sprintf("从回收站恢复 %s 失败: %v", entry.OldName, err))
+			} else {
+				entry.Undone = true
+			}
+		}
+	}
+	for i := len(journal.Entries) - 1; i >= 0; i-- {
+		entry := &journal.Entries[i]
+		if entry.pending() && entry.Op == OpMkdir {
+			if err := os.Remove(entry.path(journal.Folder)); err != nil && !os.IsNotExist(err) {
+				failures = append(failures, fmt.Sprintf("未删除新建的文件夹 %s: %v", entry.NewName, err))
+			} else {
+				entry.Undone = true
+			}
+		}
+	}
+	return failures
+}
@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useTempConfig 把配置目录指向临时目录，避免测试写入真实的重命名历史
+func useTempConfig(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Setenv("APPDATA", filepath.Join(home, "appdata"))
+}
+
+// writeTestFiles 在文件夹中创建文件，内容为映射的值
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkTestFiles 检查文件夹中的文件及其内容与映射完全一致
+func checkTestFiles(t *testing.T, dir string, want map[string]string) {
+	t.Helper()
+	got := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		got[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("文件夹内容为 %v，应为 %v", got, want)
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Fatalf("文件夹内容为 %v，应为 %v", got, want)
+		}
+	}
+}
+
+func TestUndoRenameRestoresOriginals(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	original := map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"}
+	writeTestFiles(t, dir, original)
+
+	// 含一次交换，撤销时同样需要按依赖顺序恢复
+	err := RenameFiles(dir, []ExcelData{
+		{Row: 2, OldName: "a.txt", NewName: "b.txt"},
+		{Row: 3, OldName: "b.txt", NewName: "a.txt"},
+		{Row: 4, OldName: "c.txt", NewName: "sub/c2.txt"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTestFiles(t, dir, map[string]string{"a.txt": "b", "b.txt": "a", "sub/c2.txt": "c"})
+
+	journal, err := LastRenameJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if journal.SuccessCount() != 3 {
+		t.Fatalf("成功行数为 %d，应为 3", journal.SuccessCount())
+	}
+	if err := UndoRename(journal); err != nil {
+		t.Fatal(err)
+	}
+	if !journal.Undone() {
+		t.Fatal("撤销后日志应标记为已撤销")
+	}
+	for name, content := range original {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(got) != content {
+			t.Fatalf("%s 未恢复: %q, %v", name, got, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "c2.txt")); !os.IsNotExist(err) {
+		t.Fatal("撤销后新文件名不应存在")
+	}
+}
+
+func TestUndoRenameRefusesChangedFiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(path string) error
+	}{
+		{"内容和大小改变", func(path string) error {
+			return os.WriteFile(path, []byte("changed after rename"), 0644)
+		}},
+		{"只有修改时间改变", func(path string) error {
+			later := time.Now().Add(time.Hour)
+			return os.Chtimes(path, later, later)
+		}},
+		{"文件被删除", os.Remove},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempConfig(t)
+			dir := t.TempDir()
+			writeTestFiles(t, dir, map[string]string{"a.txt": "a", "b.txt": "b"})
+			err := RenameFiles(dir, []ExcelData{
+				{Row: 2, OldName: "a.txt", NewName: "x.txt"},
+				{Row: 3, OldName: "b.txt", NewName: "y.txt"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			journal, err := LastRenameJournal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.modify(filepath.Join(dir, "y.txt")); err != nil {
+				t.Fatal(err)
+			}
+
+			if problems := CheckUndo(journal); len(problems) == 0 {
+				t.Fatal("文件被修改后 CheckUndo 应报告问题")
+			}
+			if err := UndoRename(journal); err == nil {
+				t.Fatal("文件被修改后应拒绝撤销")
+			}
+			// 整体校验失败时不应撤销任何一行
+			if _, err := os.Stat(filepath.Join(dir, "x.txt")); err != nil {
+				t.Fatalf("拒绝撤销时不应修改其他文件: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+				t.Fatal("拒绝撤销时不应恢复任何文件")
+			}
+			if journal.Undone() {
+				t.Fatal("拒绝撤销时日志不应标记为已撤销")
+			}
+		})
+	}
+}
+
+func TestPartialUndoCanBeRetried(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"a.txt": "a"})
+	err := RenameFiles(dir, []ExcelData{
+		{Row: 2, NewName: "new", Operation: "新建文件夹"},
+		{Row: 3, OldName: "a.txt", NewName: "x.txt"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 新建的文件夹中放入了其他文件，撤销时无法删除该文件夹
+	writeTestFiles(t, dir, map[string]string{"new/keep.txt": "keep"})
+
+	journal, err := LastRenameJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UndoRename(journal); err == nil {
+		t.Fatal("新建的文件夹不为空时撤销应报告错误")
+	}
+	checkTestFiles(t, dir, map[string]string{"a.txt": "a", "new/keep.txt": "keep"})
+
+	// 部分撤销后仍可再次撤销，已恢复的行不再重复处理
+	again, err := LastRenameJournal()
+	if err != nil {
+		t.Fatalf("部分撤销后日志不应标记为已撤销: %v", err)
+	}
+	if again.ID != journal.ID {
+		t.Fatalf("最近的日志为 %s，应为 %s", again.ID, journal.ID)
+	}
+	if err := os.Remove(filepath.Join(dir, "new", "keep.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := UndoRename(again); err != nil {
+		t.Fatal(err)
+	}
+	if !again.Undone() {
+		t.Fatal("全部恢复后日志应标记为已撤销")
+	}
+	checkTestFiles(t, dir, map[string]string{"a.txt": "a"})
+	if _, err := os.Stat(filepath.Join(dir, "new")); !os.IsNotExist(err) {
+		t.Fatalf("新建的文件夹应已删除: %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizeRelPath 统一表格中相对路径的分隔符，Windows 下导出的 \ 与 / 同样视为目录分隔符
+func normalizeRelPath(name string) string {
+	return filepath.FromSlash(strings.ReplaceAll(name, `\`, "/"))
+}
+
+// matchKey 按匹配方式把文件名转换为用于比较的键，path 可以带有上级目录，
+// 忽略扩展名时只去掉最后一级的扩展名
+func matchKey(path string, mode MatchMode) string {
+	if mode == MatchExact {
+		return path
+	}
+	key := norm.NFC.String(path)
+	if mode >= MatchIgnoreCase {
+		key = cases.Fold().String(key)
+	}
+	if mode >= MatchIgnoreExtension {
+		key = strings.TrimSuffix(key, filepath.Ext(key))
+	}
+	return key
+}
+
+// nameMatcher 按匹配方式在目标文件夹的实际目录内容中查找原文件，读过的目录会被缓存
+type nameMatcher struct {
+	root string
+	mode MatchMode
+	dirs map[string][]fs.DirEntry // 相对目录 -> 目录内容
+}
+
+// newNameMatcher 创建在 root 中按 mode 查找文件的匹配器
+func newNameMatcher(root string, mode MatchMode) *nameMatcher {
+	return &nameMatcher{root: root, mode: mode, dirs: make(map[string][]fs.DirEntry)}
+}
+
+// list 读取相对目录的内容，无法读取时视为空目录
+func (m *nameMatcher) list(dir string) []fs.DirEntry {
+	entries, ok := m.dirs[dir]
+	if !ok {
+		entries, _ = os.ReadDir(filepath.Join(m.root, dir))
+		m.dirs[dir] = entries
+	}
+	return entries
+}
+
+// match 逐级匹配相对路径中的每一级目录和最后的文件名，返回所有匹配到的文件的相对路径；
+// 上级目录只按规范化和大小写匹配，扩展名只在最后一级忽略
+func (m *nameMatcher) match(rel string) []string {
+	parts := strings.Split(rel, string(filepath.Separator))
+	candidates := []string{""}
+	for i, part := range parts {
+		final := i == len(parts)-1
+		mode := m.mode
+		if !final && mode > MatchIgnoreCase {
+			mode = MatchIgnoreCase
+		}
+		key := matchKey(part, mode)
+		var next []string
+		for _, dir := range candidates {
+			for _, entry := range m.list(dir) {
+				// 上级目录可以是指向目录的符号链接，最后一级不能是目录
+				if final && entry.IsDir() || !final && !entry.IsDir() && entry.Type()&fs.ModeSymlink == 0 {
+					continue
+				}
+				if matchKey(entry.Name(), mode) != key {
+					continue
+				}
+				next = append(next, filepath.Join(dir, entry.Name()))
+			}
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		candidates = next
+	}
+	return candidates
+}
+
+// fileIndex 目标文件夹（含子文件夹）中所有文件的索引，用于在子文件夹中查找原文件
+type fileIndex struct {
+	mode   MatchMode
+	byBase map[string][]string // 按匹配方式转换后的文件名 -> 相对路径列表
+}
+
+// buildFileIndex 遍历目标文件夹及其子文件夹建立文件索引，不会进入符号链接指向的目录
+func buildFileIndex(root string, mode MatchMode) (*fileIndex, error) {
+	idx := &fileIndex{mode: mode, byBase: make(map[string][]string)}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		key := matchKey(d.Name(), mode)
+		idx.byBase[key] = append(idx.byBase[key], rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("遍历目标文件夹失败: %v", err)
+	}
+	return idx, nil
+}
+
+// find 查找相对路径以 name 结尾的所有文件，name 可以只是文件名，也可以带有部分上级目录
+func (idx *fileIndex) find(name string) []string {
+	candidates := idx.byBase[matchKey(filepath.Base(name), idx.mode)]
+	if !strings.ContainsRune(name, filepath.Separator) {
+		return candidates
+	}
+	key := matchKey(name, idx.mode)
+	var matches []string
+	for _, rel := range candidates {
+		relKey := matchKey(rel, idx.mode)
+		if relKey == key || strings.HasSuffix(relKey, string(filepath.Separator)+key) {
+			matches = append(matches, rel)
+		}
+	}
+	return matches
+}
+
+// describeMatches 列出部分匹配结果用于预览说明
+func describeMatches(matches []string) string {
+	const maxShown = 3
+	if len(matches) <= maxShown {
+		return strings.Join(matches, "、")
+	}
+	return fmt.Sprintf("%s 等 %d 个", strings.Join(matches[:maxShown], "、"), len(matches))
+}
+
+// matchedBy 返回表格中的 name 与实际路径 match 一致所需的最严格的匹配方式，
+// match 可能位于子文件夹中，只比较与 name 级数相同的末尾部分
+func matchedBy(name, match string) MatchMode {
+	tail := match
+	parts := strings.Split(match, string(filepath.Separator))
+	if n := strings.Count(name, string(filepath.Separator)) + 1; len(parts) > n {
+		tail = filepath.Join(parts[len(parts)-n:]...)
+	}
+	for _, mode := range MatchModes {
+		if matchKey(tail, mode) == matchKey(name, mode) {
+			return mode
+		}
+	}
+	return MatchIgnoreExtension
+}
+
+// useMatch 改用匹配到的实际文件，文件名与表格中不完全一致时记录匹配说明；
+// 忽略扩展名匹配且新文件名没有扩展名时，沿用原文件的扩展名，新路径由调用方按新文件名重新拼接
+func useMatch(item *PlanItem, folderPath, match string) {
+	if mode := matchedBy(item.OldName, match); mode != MatchExact {
+		item.Match = fmt.Sprintf("按“%s”匹配到 %s", mode, match)
+		if mode == MatchIgnoreExtension && item.NewName != "" && filepath.Ext(item.NewName) == "" {
+			item.NewName += filepath.Ext(match)
+		}
+	}
+	item.OldName = match
+	item.OldPath = filepath.Join(folderPath, match)
+}
+
+// matchSource 原文件不在表格给出的位置时，按匹配方式在实际目录内容中查找；
+// 唯一匹配时改用找到的文件，匹配到多个文件时标记为不唯一
+func matchSource(item *PlanItem, folderPath string, matcher *nameMatcher) {
+	if _, err := os.Lstat(item.OldPath); !os.IsNotExist(err) {
+		return
+	}
+	switch matches := matcher.match(item.OldName); len(matches) {
+	case 0:
+		// 保持原样，后续在子文件夹中查找或报告原文件不存在
+	case 1:
+		useMatch(item, folderPath, matches[0])
+	default:
+		item.Status = PlanAmbiguous
+		item.Detail = fmt.Sprintf("按“%s”匹配到多个文件: %s", matcher.mode, describeMatches(matches))
+	}
+}
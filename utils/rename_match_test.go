@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// matchTestFiles 匹配测试的初始文件，café.txt 以 macOS 常见的 NFD 形式保存
+var matchTestFiles = map[string]string{
+	norm.NFD.String("café.txt"): "cafe",
+	"Report.PDF":                "report",
+	"photo.jpg":                 "photo",
+	"dup.a":                     "a",
+	"dup.b":                     "b",
+	"Sub/Inner.txt":             "inner",
+}
+
+// matchTestRows 匹配测试的表格，原文件名与实际文件名的差别逐行放宽
+func matchTestRows() []ExcelData {
+	return []ExcelData{
+		{OldName: "café.txt", NewName: "cafe.txt"},
+		{OldName: "report.pdf", NewName: "r.pdf"},
+		{OldName: "photo", NewName: "pic"},
+		{OldName: "dup", NewName: "d"},
+		{OldName: "sub/inner.TXT", NewName: "Sub/x.txt"},
+	}
+}
+
+func TestPlanRenameMatchModes(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, matchTestFiles)
+
+	ok, missing := PlanOK, PlanSourceMissing
+	tests := []struct {
+		mode MatchMode
+		want []PlanStatus
+	}{
+		{MatchExact, []PlanStatus{missing, missing, missing, missing, missing}},
+		{MatchNormalized, []PlanStatus{ok, missing, missing, missing, missing}},
+		{MatchIgnoreCase, []PlanStatus{ok, ok, missing, missing, ok}},
+		{MatchIgnoreExtension, []PlanStatus{ok, ok, ok, PlanAmbiguous, ok}},
+	}
+	for _, tt := range tests {
+		rows := matchTestRows()
+		for i := range rows {
+			rows[i].Row = i + 2
+		}
+		plan, err := PlanRename(dir, rows, RenameOptions{Match: tt.mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, item := range plan.Items {
+			if item.Status != tt.want[i] {
+				t.Errorf("%s: 第 %d 行计划状态为 %s（%s），应为 %s", tt.mode, item.Row, item.Status, item.Detail, tt.want[i])
+			}
+			if tt.mode != MatchExact && item.Status == PlanOK && item.Match == "" {
+				t.Errorf("%s: 第 %d 行没有说明匹配到的文件", tt.mode, item.Row)
+			}
+		}
+	}
+}
+
+func TestRenameIgnoringExtensionKeepsExtension(t *testing.T) {
+	_, results, dir := runTestPlan(t, matchTestFiles, matchTestRows(), RenameOptions{Match: MatchIgnoreExtension})
+	checkStatuses(t, results, RenameSucceeded, RenameSucceeded, RenameSucceeded, RenameFailed, RenameSucceeded)
+	// 表格中的新文件名没有扩展名时沿用匹配到的文件的扩展名
+	checkTestFiles(t, dir, map[string]string{
+		"cafe.txt": "cafe", "r.pdf": "report", "pic.jpg": "photo", "dup.a": "a", "dup.b": "b", "Sub/x.txt": "inner",
+	})
+}
+
+func TestLocateInSubfoldersUsesMatchMode(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"deep/A/Song.MP3": "s"})
+	plan, err := PlanRename(dir, []ExcelData{{Row: 2, OldName: "song.mp3", NewName: "s.mp3"}}, RenameOptions{Recursive: true, Match: MatchIgnoreCase})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := plan.Items[0]
+	if item.Status != PlanOK || item.NewName != filepath.Join("deep", "A", "s.mp3") || item.Match == "" {
+		t.Fatalf("计划为 %+v，应在 deep/A 中忽略大小写找到 Song.MP3", item)
+	}
+}
@@ -0,0 +1,287 @@
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// defaultDateLayout 日期字段未指定格式时使用的格式
+const defaultDateLayout = "2006-01-02"
+
+// exifDateFields 值为日期时间的 EXIF 字段，可按 Go 的时间格式输出
+var exifDateFields = map[string]bool{"DateTime": true, "DateTimeOriginal": true, "DateTimeDigitized": true}
+
+// id3Fields 模板中可用的 ID3 字段
+var id3Fields = map[string]bool{
+	ID3Title: true, ID3Artist: true, ID3Album: true, ID3AlbumArtist: true,
+	ID3Year: true, ID3Track: true, ID3Genre: true, ID3Comment: true,
+}
+
+// pathSeparatorReplacer 把字段值中的路径分隔符替换掉，避免元数据中的 / 意外创建子文件夹
+var pathSeparatorReplacer = strings.NewReplacer("/", "_", `\`, "_")
+
+// fileMetadata 单个文件的元数据，EXIF、ID3 和哈希只在模板用到时读取
+type fileMetadata struct {
+	path       string
+	info       os.FileInfo
+	seq        int
+	exif       *exif.Exif
+	exifLoaded bool
+	id3        map[string]string
+	hashes     map[string]string
+}
+
+// checkMetadataField 检查模板字段是否受支持
+func checkMetadataField(field string) error {
+	switch {
+	case field == "name", field == "ext", field == "seq", field == "mtime", field == "size", field == "hash":
+		return nil
+	case strings.HasPrefix(field, "exif.") && len(field) > len("exif."):
+		return nil
+	case strings.HasPrefix(field, "id3."):
+		if id3Fields[strings.ToLower(strings.TrimPrefix(field, "id3."))] {
+			return nil
+		}
+	}
+	return fmt.Errorf("未知的模板字段: %s", field)
+}
+
+// lookup 取出字段的值并按格式输出
+func (m *fileMetadata) lookup(field, format string) (string, error) {
+	value, err := m.value(field, format)
+	if err != nil {
+		return "", err
+	}
+	return pathSeparatorReplacer.Replace(value), nil
+}
+
+// value 取出字段的原始值
+func (m *fileMetadata) value(field, format string) (string, error) {
+	base := filepath.Base(m.path)
+	switch {
+	case field == "name":
+		return strings.TrimSuffix(base, filepath.Ext(base)), nil
+	case field == "ext":
+		return strings.TrimPrefix(filepath.Ext(base), "."), nil
+	case field == "seq":
+		return formatNumber(strconv.Itoa(m.seq), format)
+	case field == "mtime":
+		return m.info.ModTime().Format(dateLayout(format)), nil
+	case field == "size":
+		return formatSize(m.info.Size(), format)
+	case field == "hash":
+		return m.hash(format)
+	case strings.HasPrefix(field, "exif."):
+		return m.exifValue(strings.TrimPrefix(field, "exif."), format)
+	case strings.HasPrefix(field, "id3."):
+		return m.id3Value(strings.ToLower(strings.TrimPrefix(field, "id3.")), format)
+	}
+	return "", checkMetadataField(field)
+}
+
+// exifValue 读取 EXIF 字段，日期字段按格式输出，文件没有 EXIF 或缺少该字段时视为没有值
+func (m *fileMetadata) exifValue(name, format string) (string, error) {
+	if !m.exifLoaded {
+		m.exifLoaded = true
+		f, err := os.Open(m.path)
+		if err != nil {
+			return "", fmt.Errorf("打开文件失败: %v", err)
+		}
+		x, err := exif.Decode(f)
+		f.Close()
+		if err == nil || (x != nil && !exif.IsCriticalError(err)) {
+			m.exif = x
+		}
+	}
+	if m.exif == nil {
+		return "", ErrFieldMissing
+	}
+	tag, err := m.exif.Get(exif.FieldName(name))
+	if err != nil {
+		return "", ErrFieldMissing
+	}
+
+	var value string
+	if tag.Format() == tiff.StringVal {
+		value, _ = tag.StringVal()
+	} else {
+		value = strings.Trim(tag.String(), `"`)
+	}
+	value = strings.TrimSpace(strings.TrimRight(value, "\x00"))
+	if value == "" {
+		return "", ErrFieldMissing
+	}
+	if exifDateFields[name] {
+		t, err := time.ParseInLocation("2006:01:02 15:04:05", value, time.Local)
+		if err != nil {
+			return "", ErrFieldMissing
+		}
+		return t.Format(dateLayout(format)), nil
+	}
+	return value, nil
+}
+
+// id3Value 读取 ID3 字段，音轨号和年份可用位数格式补 0
+func (m *fileMetadata) id3Value(name, format string) (string, error) {
+	if m.id3 == nil {
+		tags, err := ReadID3Tags(m.path)
+		if err != nil {
+			return "", err
+		}
+		m.id3 = tags
+	}
+	value := m.id3[name]
+	if value == "" {
+		return "", ErrFieldMissing
+	}
+	if name == ID3Track {
+		// 音轨号可能写成“3/12”的形式
+		value, _, _ = strings.Cut(value, "/")
+	}
+	if format != "" {
+		return formatNumber(value, format)
+	}
+	return value, nil
+}
+
+// hash 计算文件哈希，格式可指定算法（md5、sha1、sha256）和保留的位数，如 {hash:md5:8}
+func (m *fileMetadata) hash(format string) (string, error) {
+	algorithm, length := "sha256", 0
+	for _, option := range strings.Split(format, ":") {
+		option = strings.ToLower(strings.TrimSpace(option))
+		if option == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(option); err == nil && n > 0 {
+			length = n
+			continue
+		}
+		algorithm = option
+	}
+
+	sum, ok := m.hashes[algorithm]
+	if !ok {
+		var h hash.Hash
+		switch algorithm {
+		case "md5":
+			h = md5.New()
+		case "sha1":
+			h = sha1.New()
+		case "sha256":
+			h = sha256.New()
+		default:
+			return "", fmt.Errorf("不支持的哈希算法: %s", algorithm)
+		}
+		f, err := os.Open(m.path)
+		if err != nil {
+			return "", fmt.Errorf("打开文件失败: %v", err)
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("计算哈希失败: %v", err)
+		}
+		sum = hex.EncodeToString(h.Sum(nil))
+		if m.hashes == nil {
+			m.hashes = make(map[string]string)
+		}
+		m.hashes[algorithm] = sum
+	}
+	if length > 0 && length < len(sum) {
+		sum = sum[:length]
+	}
+	return sum, nil
+}
+
+// dateLayout 返回日期格式，未指定时使用默认格式
+func dateLayout(format string) string {
+	if format == "" {
+		return defaultDateLayout
+	}
+	return format
+}
+
+// formatNumber 按位数格式为数字补 0，如格式 03 把 7 输出为 007；值不是整数时原样输出
+func formatNumber(value, format string) (string, error) {
+	if format == "" {
+		return value, nil
+	}
+	width, err := strconv.Atoi(format)
+	if err != nil || width < 0 {
+		return "", fmt.Errorf("数字格式应为位数，如 03: %s", format)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return value, nil
+	}
+	return fmt.Sprintf("%0*d", width, n), nil
+}
+
+// formatSize 输出文件大小，格式可为 kb、mb、gb，未指定时输出字节数
+func formatSize(size int64, format string) (string, error) {
+	units := map[string]float64{"kb": 1 << 10, "mb": 1 << 20, "gb": 1 << 30}
+	if format == "" {
+		return strconv.FormatInt(size, 10), nil
+	}
+	unit, ok := units[strings.ToLower(format)]
+	if !ok {
+		return "", fmt.Errorf("文件大小格式应为 kb、mb 或 gb: %s", format)
+	}
+	return fmt.Sprintf("%.1f%s", float64(size)/unit, strings.ToUpper(format)), nil
+}
+
+// BuildTemplateRenames 按模板为文件夹中的文件生成新文件名，结果可直接交给 PlanRename。
+// 模板支持的字段有 name、ext、seq、mtime、size、hash、exif.字段名 和 id3.字段名，
+// 序号按自然排序从 1 开始；某个文件无法生成文件名时该行只在预览中报告原因
+func BuildTemplateRenames(folderPath, source, filter string) ([]ExcelData, error) {
+	t, err := ParseTemplate(source)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range t.Fields() {
+		if err := checkMetadataField(field); err != nil {
+			return nil, err
+		}
+	}
+	if filter != "" {
+		if _, err := filepath.Match(filter, ""); err != nil {
+			return nil, fmt.Errorf("文件筛选条件无效: %v", err)
+		}
+	}
+	names, err := ListFolderFiles(folderPath, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]ExcelData, len(names))
+	for i, name := range names {
+		data[i] = ExcelData{Row: i + 1, OldName: name}
+		path := filepath.Join(folderPath, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			data[i].Problem = fmt.Sprintf("读取文件信息失败: %v", err)
+			continue
+		}
+		m := &fileMetadata{path: path, info: info, seq: i + 1}
+		newName, err := t.Execute(m.lookup)
+		if err != nil {
+			data[i].Problem = err.Error()
+			continue
+		}
+		data[i].NewName = newName
+	}
+	return data, nil
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// id3Frame 生成 ID3v2.3 的 UTF-8 文本帧
+func id3Frame(id, text string) []byte {
+	frame := binary.BigEndian.AppendUint32([]byte(id), uint32(len(text)+1))
+	frame = append(frame, 0, 0, 3)
+	return append(frame, text...)
+}
+
+func TestBuildTemplateRenames(t *testing.T) {
+	dir := t.TempDir()
+	var body []byte
+	body = append(body, id3Frame("TPE1", "AC/DC")...)
+	body = append(body, id3Frame("TIT2", "歌曲")...)
+	body = append(body, id3Frame("TRCK", "3/12")...)
+	n := len(body)
+	tag := append([]byte("ID3\x03\x00\x00"), byte(n>>21&0x7f), byte(n>>14&0x7f), byte(n>>7&0x7f), byte(n&0x7f))
+	writeTestFiles(t, dir, map[string]string{"song.mp3": string(append(tag, body...)), "x.txt": "hi", "y.txt": "hello"})
+	mtime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)
+	if err := os.Chtimes(filepath.Join(dir, "x.txt"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		template, filter string
+		want             []string
+	}{
+		// 字段值中的 / 被替换，音轨号只取 / 前面的部分
+		{"{id3.artist} - {id3.title} {id3.track:02}.mp3", "*.mp3", []string{"AC_DC - 歌曲 03.mp3"}},
+		{"{mtime:20060102}_{hash:md5:8}_{size}.{ext}", "x.txt", []string{"20240506_49f68a5c_2.txt"}},
+		// 缺少的字段使用 | 后的备选字段
+		{"{seq:02}_{exif.Model|name}.{ext}", "*.txt", []string{"01_x.txt", "02_y.txt"}},
+	}
+	for _, tt := range tests {
+		data, err := BuildTemplateRenames(dir, tt.template, tt.filter)
+		if err != nil {
+			t.Fatalf("模板 %q: %v", tt.template, err)
+		}
+		if len(data) != len(tt.want) {
+			t.Fatalf("模板 %q 生成 %d 行，应为 %d 行", tt.template, len(data), len(tt.want))
+		}
+		for i, row := range data {
+			if row.NewName != tt.want[i] || row.Problem != "" {
+				t.Errorf("模板 %q 把 %s 改为 %q（%s），应为 %q", tt.template, row.OldName, row.NewName, row.Problem, tt.want[i])
+			}
+		}
+	}
+
+	// 文件缺少字段时该行只报告原因
+	data, err := BuildTemplateRenames(dir, "{id3.album}.{ext}", "*.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0].NewName != "" || data[0].Problem == "" {
+		t.Errorf("缺少字段时新文件名为 %q，原因为 %q，应只报告原因", data[0].NewName, data[0].Problem)
+	}
+	if _, err := BuildTemplateRenames(dir, "{bogus}", ""); err == nil {
+		t.Error("未知字段应报错")
+	}
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operation 表格中每一行要执行的操作
+type Operation int
+
+const (
+	OpRename Operation = iota // 重命名，操作列为空时的默认操作
+	OpMove                    // 移动，新文件名是文件夹（以 / 结尾或已存在的文件夹）时保留原文件名
+	OpCopy                    // 复制，原文件保持不变
+	OpDelete                  // 删除，移到回收站而不是永久删除，只使用原文件名
+	OpMkdir                   // 新建文件夹，使用新文件名，新文件名为空时使用原文件名
+)
+
+// Operations 按界面显示顺序列出所有操作
+var Operations = []Operation{OpRename, OpMove, OpCopy, OpDelete, OpMkdir}
+
+var operationNames = map[Operation]string{
+	OpRename: "重命名",
+	OpMove:   "移动",
+	OpCopy:   "复制",
+	OpDelete: "删除",
+	OpMkdir:  "新建文件夹",
+}
+
+// operationAliases 操作列中可以使用的写法，比较时不区分大小写
+var operationAliases = map[string]Operation{
+	"rename": OpRename, "ren": OpRename, "改名": OpRename,
+	"move": OpMove, "mv": OpMove,
+	"copy": OpCopy, "cp": OpCopy,
+	"delete": OpDelete, "del": OpDelete, "rm": OpDelete, "trash": OpDelete, "回收站": OpDelete, "移到回收站": OpDelete,
+	"mkdir": OpMkdir, "md": OpMkdir, "新建目录": OpMkdir, "创建文件夹": OpMkdir,
+}
+
+// String 返回操作的中文名称
+func (op Operation) String() string {
+	if name, ok := operationNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知操作(%d)", int(op))
+}
+
+// ParseOperation 解析操作列的内容，可以是中文名称或 rename、move、copy、delete、mkdir 等英文写法，
+// 为空时为重命名
+func ParseOperation(text string) (Operation, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return OpRename, nil
+	}
+	for op, name := range operationNames {
+		if text == name {
+			return op, nil
+		}
+	}
+	if op, ok := operationAliases[text]; ok {
+		return op, nil
+	}
+	return OpRename, fmt.Errorf("未知的操作: %s，可用的操作有 重命名、移动、复制、删除、新建文件夹", text)
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOperationsRunAndUndo(t *testing.T) {
+	files := map[string]string{"a.txt": "A", "b.txt": "B", "c.txt": "C", "existing/keep.txt": "K"}
+	rows := []ExcelData{
+		{Operation: "mkdir", NewName: "archive/2024"},
+		{Operation: "移动", OldName: "a.txt", NewName: "archive/2024/"},
+		{Operation: "copy", OldName: "b.txt", NewName: "archive/b-copy.txt"},
+		{OldName: "b.txt", NewName: "b2.txt"},
+		{Operation: "rename", OldName: "c.txt", NewName: "d.txt"},
+		{Operation: "mv", OldName: "existing/keep.txt", NewName: "existing"},
+		{Operation: "explode", OldName: "c.txt", NewName: "z.txt"},
+		{Operation: "新建文件夹", NewName: "existing"},
+	}
+	plan, results, dir := runTestPlan(t, files, rows, RenameOptions{})
+
+	// 新文件名以分隔符结尾或是已有文件夹时移入该文件夹
+	if want := filepath.Join("archive", "2024", "a.txt"); plan.Items[1].NewName != want {
+		t.Errorf("移动的新文件名为 %s，应为 %s", plan.Items[1].NewName, want)
+	}
+	want := []PlanStatus{PlanOK, PlanOK, PlanOK, PlanOK, PlanOK, PlanUnchanged, PlanInvalidOperation, PlanUnchanged}
+	for i, item := range plan.Items {
+		if item.Status != want[i] {
+			t.Errorf("第 %d 行计划状态为 %s（%s），应为 %s", item.Row, item.Status, item.Detail, want[i])
+		}
+	}
+	checkStatuses(t, results, RenameSucceeded, RenameSucceeded, RenameSucceeded, RenameSucceeded, RenameSucceeded,
+		RenameSkipped, RenameFailed, RenameSkipped)
+	checkTestFiles(t, dir, map[string]string{
+		"archive/2024/a.txt": "A", "archive/b-copy.txt": "B", "b2.txt": "B", "d.txt": "C", "existing/keep.txt": "K",
+	})
+
+	journal, err := LastRenameJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := CheckUndo(journal); len(problems) > 0 {
+		t.Fatalf("撤销前检查发现问题: %v", problems)
+	}
+	if err := UndoRename(journal); err != nil {
+		t.Fatal(err)
+	}
+	// 撤销会删除复制出的文件和新建的文件夹，原本就存在的文件夹保留
+	checkTestFiles(t, dir, files)
+	if _, err := os.Stat(filepath.Join(dir, "archive", "2024")); !os.IsNotExist(err) {
+		t.Errorf("撤销后新建的文件夹仍然存在: %v", err)
+	}
+}
+
+func TestPlanRenameRejectsConflictingOperations(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"a.txt": "A"})
+
+	// 复制模式下不能删除
+	plan, err := PlanRename(dir, []ExcelData{{Row: 2, Operation: "rm", OldName: "a.txt"}}, RenameOptions{Output: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := plan.Items[0].Status; status != PlanInvalidOperation {
+		t.Errorf("复制模式下删除的计划状态为 %s，应为 %s", status, PlanInvalidOperation)
+	}
+	// 同一个文件只能被一行移走
+	rows := []ExcelData{{Row: 2, Operation: "rm", OldName: "a.txt"}, {Row: 3, OldName: "a.txt", NewName: "q.txt"}}
+	if plan, err = PlanRename(dir, rows, RenameOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if status := plan.Items[1].Status; status != PlanDuplicateSource {
+		t.Errorf("第二次移走同一文件的计划状态为 %s，应为 %s", status, PlanDuplicateSource)
+	}
+}
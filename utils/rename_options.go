@@ -0,0 +1,98 @@
+package utils
+
+import "fmt"
+
+// CollisionPolicy 目标文件名冲突时的处理方式
+type CollisionPolicy int
+
+const (
+	CollisionSkip      CollisionPolicy = iota // 跳过冲突的行
+	CollisionOverwrite                        // 覆盖已存在的目标文件
+	CollisionSuffix                           // 在文件名后追加 (1)、(2) 等序号
+	CollisionFail                             // 有任何冲突时中止整批重命名
+)
+
+// CollisionPolicies 按界面显示顺序列出所有冲突处理方式
+var CollisionPolicies = []CollisionPolicy{CollisionSkip, CollisionOverwrite, CollisionSuffix, CollisionFail}
+
+var collisionPolicyNames = map[CollisionPolicy]string{
+	CollisionSkip:      "跳过",
+	CollisionOverwrite: "覆盖",
+	CollisionSuffix:    "自动追加序号",
+	CollisionFail:      "整批中止",
+}
+
+// String 返回冲突处理方式的中文名称
+func (p CollisionPolicy) String() string {
+	if name, ok := collisionPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知方式(%d)", int(p))
+}
+
+// ParseCollisionPolicy 根据中文名称解析冲突处理方式
+func ParseCollisionPolicy(name string) (CollisionPolicy, error) {
+	for policy, policyName := range collisionPolicyNames {
+		if policyName == name {
+			return policy, nil
+		}
+	}
+	return CollisionSkip, fmt.Errorf("未知的冲突处理方式: %s", name)
+}
+
+// MatchMode 表格中的原文件名与目录中实际文件名的匹配方式，
+// 每种方式都包含前一种方式，只有按原样找不到文件时才会使用
+type MatchMode int
+
+const (
+	MatchExact           MatchMode = iota // 文件名完全一致
+	MatchNormalized                       // Unicode 规范化后一致，如 macOS 的 NFD 与 Windows 的 NFC
+	MatchIgnoreCase                       // 规范化后忽略大小写
+	MatchIgnoreExtension                  // 规范化、忽略大小写且忽略扩展名
+)
+
+// MatchModes 按界面显示顺序列出所有匹配方式
+var MatchModes = []MatchMode{MatchExact, MatchNormalized, MatchIgnoreCase, MatchIgnoreExtension}
+
+var matchModeNames = map[MatchMode]string{
+	MatchExact:           "完全一致",
+	MatchNormalized:      "忽略 Unicode 编码形式",
+	MatchIgnoreCase:      "忽略大小写",
+	MatchIgnoreExtension: "忽略扩展名",
+}
+
+// String 返回匹配方式的中文名称
+func (m MatchMode) String() string {
+	if name, ok := matchModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知方式(%d)", int(m))
+}
+
+// ParseMatchMode 根据中文名称解析匹配方式
+func ParseMatchMode(name string) (MatchMode, error) {
+	for mode, modeName := range matchModeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+	return MatchExact, fmt.Errorf("未知的匹配方式: %s", name)
+}
+
+// RenameOptions 重命名选项，零值即默认设置
+type RenameOptions struct {
+	Collision CollisionPolicy  // 目标文件名冲突时的处理方式，默认跳过
+	Recursive bool             // 原文件不在指定位置时，在所有子文件夹中查找
+	Match     MatchMode        // 原文件名与实际文件名的匹配方式，默认完全一致
+	Output    string           // 非空时为复制模式：原文件保持不变，按新文件名把副本写入该文件夹
+	Copy      CopyMethod       // 复制模式下生成副本的方式
+	Platforms []TargetPlatform // 新文件名需要兼容的目标平台，为空时不检查
+	Sanitize  bool             // 自动修正在目标平台上不可用的新文件名，否则标记为不兼容
+	Backup    BackupMode       // 执行前把受影响的文件或其清单备份到压缩包
+	Retries   int              // 文件被其他进程占用时每行最多重试的次数，每次等待的时间加倍，为 0 时不重试
+}
+
+// CopyMode 判断是否为复制模式
+func (o RenameOptions) CopyMode() bool {
+	return o.Output != ""
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// PairOrder 按顺序重命名时文件的排列方式，排好后第 1 个文件对应表格中的第 1 个新文件名，依此类推
+type PairOrder int
+
+const (
+	OrderNatural PairOrder = iota // 文件名的自然顺序，如 IMG_2 排在 IMG_10 之前
+	OrderModTime                  // 修改时间从早到晚
+	OrderEXIF                     // 照片拍摄时间从早到晚，没有拍摄时间的文件使用修改时间
+)
+
+// PairOrders 按界面显示顺序列出所有排列方式
+var PairOrders = []PairOrder{OrderNatural, OrderModTime, OrderEXIF}
+
+var pairOrderNames = map[PairOrder]string{
+	OrderNatural: "文件名顺序",
+	OrderModTime: "修改时间",
+	OrderEXIF:    "拍摄时间（EXIF）",
+}
+
+// String 返回排列方式的中文名称
+func (o PairOrder) String() string {
+	if name, ok := pairOrderNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知排列方式(%d)", int(o))
+}
+
+// ParsePairOrder 根据中文名称解析排列方式
+func ParsePairOrder(name string) (PairOrder, error) {
+	for order, orderName := range pairOrderNames {
+		if orderName == name {
+			return order, nil
+		}
+	}
+	return OrderNatural, fmt.Errorf("未知的排列方式: %s", name)
+}
+
+// OrderPairing 按顺序配对的结果
+type OrderPairing struct {
+	Data  []ExcelData // 配对的行在前，多出的文件或新文件名作为无法生成的行列在最后，结果可直接交给 PlanRename
+	Keys  []string    // 每行文件的排序依据，如修改时间，与 Data 一一对应，没有文件的行为空
+	Files int         // 参与配对的文件数
+	Names int         // 表格中的新文件名数
+}
+
+// Warning 文件数与新文件名数不一致时返回提示，一致时返回空字符串
+func (p OrderPairing) Warning() string {
+	switch {
+	case p.Files > p.Names:
+		return fmt.Sprintf("数量不一致：文件夹中有 %d 个文件，表格中只有 %d 个新文件名，排在最后的 %d 个文件不会被重命名",
+			p.Files, p.Names, p.Files-p.Names)
+	case p.Files < p.Names:
+		return fmt.Sprintf("数量不一致：表格中有 %d 个新文件名，文件夹中只有 %d 个文件，最后 %d 个新文件名没有对应的文件",
+			p.Names, p.Files, p.Names-p.Files)
+	}
+	return ""
+}
+
+// orderedFile 排序时使用的文件信息
+type orderedFile struct {
+	name string
+	time time.Time
+	key  string
+}
+
+// BuildOrderRenames 把文件夹中的文件按指定方式排列后，与表格中的新文件名逐个配对。
+// 新文件名没有写扩展名时保留原文件的扩展名，如 IMG_0001.jpg 配对“日落”后为 日落.jpg；写了扩展名时按写的为准
+func BuildOrderRenames(folderPath, filter string, order PairOrder, names []ExcelData) (OrderPairing, error) {
+	if filter != "" {
+		if _, err := filepath.Match(filter, ""); err != nil {
+			return OrderPairing{}, fmt.Errorf("文件筛选条件无效: %v", err)
+		}
+	}
+	files, err := sortFolderFiles(folderPath, filter, order)
+	if err != nil {
+		return OrderPairing{}, err
+	}
+
+	pairing := OrderPairing{Files: len(files), Names: len(names)}
+	for i := 0; i < len(files) || i < len(names); i++ {
+		switch {
+		case i >= len(names):
+			pairing.Data = append(pairing.Data, ExcelData{OldName: files[i].name, Problem: "表格中没有对应的新文件名"})
+			pairing.Keys = append(pairing.Keys, files[i].key)
+		case i >= len(files):
+			pairing.Data = append(pairing.Data, ExcelData{Row: names[i].Row, NewName: names[i].NewName, Problem: "文件夹中没有对应的文件"})
+			pairing.Keys = append(pairing.Keys, "")
+		default:
+			newName := keepOriginalExt(files[i].name, names[i].NewName)
+			pairing.Data = append(pairing.Data, ExcelData{Row: names[i].Row, OldName: files[i].name, NewName: newName})
+			pairing.Keys = append(pairing.Keys, files[i].key)
+		}
+	}
+	return pairing, nil
+}
+
+// sortFolderFiles 列出文件夹中的文件并按指定方式排列，时间相同的文件按文件名的自然顺序排列
+func sortFolderFiles(folderPath, filter string, order PairOrder) ([]orderedFile, error) {
+	names, err := ListFolderFiles(folderPath, filter)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]orderedFile, len(names))
+	for i, name := range names {
+		files[i].name = name
+		if order == OrderNatural {
+			continue
+		}
+		path := filepath.Join(folderPath, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取文件信息失败: %v", err)
+		}
+		files[i].time = info.ModTime()
+		files[i].key = "修改时间 " + info.ModTime().Format("2006-01-02 15:04:05")
+		if order == OrderEXIF {
+			if taken, ok := photoTakenTime(path); ok {
+				files[i].time = taken
+				files[i].key = "拍摄时间 " + taken.Format("2006-01-02 15:04:05")
+			}
+		}
+	}
+
+	// ListFolderFiles 已按自然顺序排列，稳定排序保证时间相同时仍按文件名排列
+	if order != OrderNatural {
+		sort.SliceStable(files, func(i, j int) bool {
+			return files[i].time.Before(files[j].time)
+		})
+	}
+	return files, nil
+}
+
+// photoTakenTime 读取照片 EXIF 中的拍摄时间，没有 EXIF 或拍摄时间时返回 false
+func photoTakenTime(path string) (time.Time, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	x, err := exif.Decode(f)
+	if x == nil || (err != nil && exif.IsCriticalError(err)) {
+		return time.Time{}, false
+	}
+	taken, err := x.DateTime()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return taken, true
+}
@@ -0,0 +1,37 @@
+package utils
+
+import "testing"
+
+func TestBuildOrderRenamesKeepsOnlyMissingExtension(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"IMG_1.jpg": "1", "IMG_2.jpg": "2", "IMG_10.jpg": "10", "IMG_11.jpg": "11", "IMG_12.jpg": "12", "IMG_13.jpg": "13"})
+	names := []ExcelData{
+		{Row: 2, NewName: "日落"},
+		{Row: 3, NewName: "x.png"},
+		{Row: 4, NewName: "海边.JPG"},
+		{Row: 5, NewName: "Dr. Who"},
+		{Row: 6, NewName: "Vol. 2"},
+		{Row: 7, NewName: "第1.5集"},
+	}
+
+	pairing, err := BuildOrderRenames(dir, "", OrderNatural, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ExcelData{
+		{Row: 2, OldName: "IMG_1.jpg", NewName: "日落.jpg"},
+		{Row: 3, OldName: "IMG_2.jpg", NewName: "x.png"},
+		{Row: 4, OldName: "IMG_10.jpg", NewName: "海边.JPG"},
+		{Row: 5, OldName: "IMG_11.jpg", NewName: "Dr. Who.jpg"},
+		{Row: 6, OldName: "IMG_12.jpg", NewName: "Vol. 2.jpg"},
+		{Row: 7, OldName: "IMG_13.jpg", NewName: "第1.5集.jpg"},
+	}
+	if len(pairing.Data) != len(want) {
+		t.Fatalf("配对结果为 %+v，应为 %+v", pairing.Data, want)
+	}
+	for i := range want {
+		if pairing.Data[i] != want[i] {
+			t.Errorf("第 %d 个配对为 %+v，应为 %+v", i+1, pairing.Data[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,700 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PlanStatus 重命名计划中单行的状态
+type PlanStatus int
+
+const (
+	PlanOK               PlanStatus = iota // 可以直接重命名
+	PlanChain                              // 目标是另一行的原文件，需等该行先重命名
+	PlanOverwrite                          // 目标已存在或重复，按设置覆盖
+	PlanAutoSuffix                         // 目标已存在或重复，按设置追加序号
+	PlanUnchanged                          // 新旧文件名相同，无需处理
+	PlanSourceMissing                      // 原文件不存在
+	PlanTargetExists                       // 目标文件已存在
+	PlanDuplicateTarget                    // 与其他行的新文件名重复
+	PlanDuplicateSource                    // 与其他行的原文件名重复
+	PlanUnsafe                             // 文件名为空或路径超出目标文件夹
+	PlanAmbiguous                          // 在子文件夹中匹配到多个同名文件
+	PlanInvalidName                        // 无法按模板或规则生成新文件名
+	PlanInvalidOperation                   // 操作列的内容无法识别或不能在当前模式下执行
+	PlanIncompatibleName                   // 新文件名在目标平台上不可用
+
+	planStatusCount // 状态数量，仅用于遍历
+)
+
+var planStatusNames = map[PlanStatus]string{
+	PlanOK:               "可重命名",
+	PlanChain:            "链式重命名",
+	PlanOverwrite:        "覆盖目标",
+	PlanAutoSuffix:       "追加序号",
+	PlanUnchanged:        "无需修改",
+	PlanSourceMissing:    "原文件不存在",
+	PlanTargetExists:     "目标已存在",
+	PlanDuplicateTarget:  "新文件名重复",
+	PlanDuplicateSource:  "原文件名重复",
+	PlanUnsafe:           "路径不安全",
+	PlanAmbiguous:        "匹配不唯一",
+	PlanInvalidName:      "无法生成文件名",
+	PlanInvalidOperation: "操作无效",
+	PlanIncompatibleName: "文件名不兼容",
+}
+
+// String 返回状态的中文名称
+func (s PlanStatus) String() string {
+	if name, ok := planStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知状态(%d)", int(s))
+}
+
+// PlanItem 重命名计划中的一行
+type PlanItem struct {
+	Row     int // Excel中的行号
+	Op      Operation
+	OldName string
+	NewName string
+	OldPath string
+	NewPath string
+	Status  PlanStatus
+	Detail  string // 补充说明，如依赖的行号、重复的行号
+	Match   string // 没有按原样找到原文件时，实际匹配到的文件及匹配方式
+	NameFix string // 按目标平台自动修正新文件名时的改动，如 CON[→_].txt，未修正时为空
+}
+
+// Runnable 判断该行确认后是否会被执行
+func (item PlanItem) Runnable() bool {
+	switch item.Status {
+	case PlanOK, PlanChain, PlanOverwrite, PlanAutoSuffix:
+		return true
+	}
+	return false
+}
+
+// Conflict 判断该行是否存在目标文件名冲突
+func (item PlanItem) Conflict() bool {
+	return item.Status == PlanTargetExists || item.Status == PlanDuplicateTarget
+}
+
+// RenamePlan 重命名计划，生成时不会修改磁盘上的任何文件
+type RenamePlan struct {
+	FolderPath   string
+	Options      RenameOptions
+	Items        []PlanItem
+	CopyProgress CopyProgressCallback // 目标在另一个磁盘或分区上、需要复制文件时报告字节进度，可以为 nil
+
+	BackupProgress ProgressCallback // 执行前备份时按文件报告进度，可以为 nil
+	BackupPath     string           // 执行时生成的备份压缩包，未备份时为空
+
+	journal *RenameJournal // 执行时写入的重命名日志，多文件夹执行失败时用于回滚
+}
+
+// Count 统计指定状态的行数
+func (p *RenamePlan) Count(status PlanStatus) int {
+	count := 0
+	for _, item := range p.Items {
+		if item.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// ConflictCount 统计存在目标文件名冲突的行数
+func (p *RenamePlan) ConflictCount() int {
+	count := 0
+	for _, item := range p.Items {
+		if item.Conflict() {
+			count++
+		}
+	}
+	return count
+}
+
+// RunnableCount 统计可执行的行数
+func (p *RenamePlan) RunnableCount() int {
+	count := 0
+	for _, item := range p.Items {
+		if item.Runnable() {
+			count++
+		}
+	}
+	return count
+}
+
+// copies 判断该行是否复制文件：操作为复制的行以及复制模式下的所有文件行，原文件都会保留
+func (p *RenamePlan) copies(item PlanItem) bool {
+	return item.Op == OpCopy || p.Options.CopyMode() && item.Op != OpMkdir
+}
+
+// vacates 判断该行执行后原文件名是否会被腾出：重命名、移动和删除
+func (p *RenamePlan) vacates(item PlanItem) bool {
+	return item.Op != OpMkdir && !p.copies(item)
+}
+
+// TargetFolder 新文件所在的文件夹：复制模式下为输出文件夹，否则为目标文件夹本身
+func (p *RenamePlan) TargetFolder() string {
+	if p.Options.CopyMode() {
+		return p.Options.Output
+	}
+	return p.FolderPath
+}
+
+// SanitizedCount 统计自动修正了新文件名的行数
+func (p *RenamePlan) SanitizedCount() int {
+	count := 0
+	for _, item := range p.Items {
+		if item.NameFix != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// MatchedCount 统计不是按原样、而是按匹配方式找到原文件的行数
+func (p *RenamePlan) MatchedCount() int {
+	count := 0
+	for _, item := range p.Items {
+		if item.Match != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// Summary 返回计划的统计摘要
+func (p *RenamePlan) Summary() string {
+	parts := []string{fmt.Sprintf("共 %d 行", len(p.Items)), fmt.Sprintf("可执行 %d 行", p.RunnableCount())}
+	if p.Options.CopyMode() {
+		parts = append([]string{fmt.Sprintf("复制模式（%s）: 副本写入 %s", p.Options.Copy, p.Options.Output)}, parts...)
+	}
+	if p.Options.Backup != BackupNone {
+		parts = append(parts, "执行前备份: "+p.Options.Backup.String())
+	}
+	for status := PlanChain; status < planStatusCount; status++ {
+		if count := p.Count(status); count > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d 行", status, count))
+		}
+	}
+	if count := p.MatchedCount(); count > 0 {
+		parts = append(parts, fmt.Sprintf("非完全一致匹配 %d 行", count))
+	}
+	if count := p.SanitizedCount(); count > 0 {
+		parts = append(parts, fmt.Sprintf("自动修正文件名 %d 行", count))
+	}
+	return strings.Join(parts, "，")
+}
+
+// renamePlanner 逐行把表格数据加入重命名计划，全部加入后再检查行与行之间的重复、冲突和依赖
+type renamePlanner struct {
+	plan           *RenamePlan
+	targetFolder   string         // 新文件名所在的文件夹，复制模式下为输出文件夹
+	resolvedRoot   string         // 解析符号链接后的目标文件夹
+	resolvedTarget string         // 解析符号链接后的 targetFolder
+	sources        map[string]int // 原路径 -> 第一次出现的下标
+	index          *fileIndex     // 在子文件夹中查找时才建立
+	matcher        *nameMatcher   // 不要求完全一致时才建立
+}
+
+// PlanRename 根据Excel数据生成重命名计划（试运行），检查路径是否安全、原文件是否存在、
+// 目标是否已存在、新文件名是否重复以及行与行之间的链式依赖，
+// 并按选项中的冲突处理方式决定冲突的行如何执行
+func PlanRename(folderPath string, renameData []ExcelData, options RenameOptions) (*RenamePlan, error) {
+	p, err := newRenamePlanner(folderPath, options)
+	if err != nil {
+		return nil, err
+	}
+	p.plan.Items = make([]PlanItem, 0, len(renameData))
+	for _, data := range renameData {
+		if err := p.add(data); err != nil {
+			return nil, err
+		}
+	}
+	return p.finish()
+}
+
+// PlanRenameTable 边读取重命名表格边生成计划，读到的每一行直接检查并加入计划，不再先收集成完整的数据列表；
+// 重复、冲突和依赖要看到所有行才能判断，在读完表格后检查
+func PlanRenameTable(folderPath, tablePath string, mapping ColumnMapping, options RenameOptions, progressCallback ...ProgressCallback) (*RenamePlan, error) {
+	p, err := newRenamePlanner(folderPath, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := StreamRenameTable(tablePath, mapping, p.add, progressCallback...); err != nil {
+		return nil, err
+	}
+	return p.finish()
+}
+
+// newRenamePlanner 检查目标文件夹和输出文件夹，准备逐行生成计划
+func newRenamePlanner(folderPath string, options RenameOptions) (*renamePlanner, error) {
+	info, err := os.Stat(folderPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取目标文件夹失败: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("目标路径不是文件夹: %s", folderPath)
+	}
+	resolvedRoot, err := filepath.EvalSymlinks(folderPath)
+	if err != nil {
+		return nil, fmt.Errorf("解析目标文件夹失败: %v", err)
+	}
+
+	plan := &RenamePlan{FolderPath: folderPath, Options: options}
+	targetFolder, resolvedTarget := folderPath, resolvedRoot
+	if options.CopyMode() {
+		// 复制模式：新文件名相对于输出文件夹，输出文件夹可以尚不存在，执行时再创建
+		targetFolder = plan.TargetFolder()
+		if resolvedTarget, err = resolveExisting(targetFolder); err != nil {
+			return nil, fmt.Errorf("解析输出文件夹失败: %v", err)
+		}
+		if resolvedTarget == resolvedRoot {
+			return nil, fmt.Errorf("输出文件夹不能与目标文件夹相同")
+		}
+	}
+	return &renamePlanner{
+		plan:           plan,
+		targetFolder:   targetFolder,
+		resolvedRoot:   resolvedRoot,
+		resolvedTarget: resolvedTarget,
+		sources:        make(map[string]int),
+	}, nil
+}
+
+// add 检查一行的操作、文件名和路径，找到原文件后加入计划
+func (p *renamePlanner) add(data ExcelData) error {
+	plan, options := p.plan, p.plan.Options
+	folderPath, targetFolder := plan.FolderPath, p.targetFolder
+	resolvedRoot, resolvedTarget := p.resolvedRoot, p.resolvedTarget
+	i := len(plan.Items)
+
+	row := data.Row
+	if row == 0 {
+		row = i + 1
+	}
+	op, opErr := ParseOperation(data.Operation)
+	oldName, newName := normalizeRelPath(data.OldName), normalizeRelPath(data.NewName)
+	if op == OpMkdir && strings.TrimSpace(newName) == "" {
+		newName = oldName
+	}
+
+	// 按目标平台检查新文件名，开启自动修正时先修正再检查，修正后仍不可用（如路径过长）时同样标出
+	var nameFix string
+	var incompatible []string
+	if len(options.Platforms) > 0 && op != OpDelete && opErr == nil && data.Problem == "" {
+		if fixed := SanitizeFileName(newName, options.Platforms); options.Sanitize && fixed != newName {
+			nameFix = DescribeNameChange(newName, fixed)
+			newName = fixed
+		}
+		incompatible = ValidateFileName(newName, options.Platforms)
+	}
+
+	item := PlanItem{
+		Row:     row,
+		Op:      op,
+		OldName: data.OldName,
+		NewName: data.NewName,
+		OldPath: filepath.Join(folderPath, oldName),
+		NewPath: filepath.Join(targetFolder, newName),
+		NameFix: nameFix,
+	}
+
+	// 无法生成新文件名、操作无效、文件名不兼容或不安全的行只在预览中报告，不参与后续检查
+	if data.Problem != "" {
+		item.Status = PlanInvalidName
+		item.Detail = data.Problem
+	} else if opErr != nil {
+		item.Status = PlanInvalidOperation
+		item.Detail = opErr.Error()
+	} else if op == OpDelete && options.CopyMode() {
+		item.Status = PlanInvalidOperation
+		item.Detail = "复制模式下不能删除文件"
+	} else if len(incompatible) > 0 {
+		item.Status = PlanIncompatibleName
+		item.Detail = strings.Join(incompatible, "；")
+	} else if op == OpMkdir {
+		// 新建文件夹只有目标，没有原文件
+		item.OldName, item.OldPath = "", ""
+		if _, err := checkPathInFolder(targetFolder, resolvedTarget, newName); err != nil {
+			item.Status = PlanUnsafe
+			item.Detail = fmt.Sprintf("文件夹名: %v", err)
+		} else {
+			item.NewName = filepath.Clean(newName)
+			item.NewPath = filepath.Join(targetFolder, item.NewName)
+		}
+	} else if _, err := checkPathInFolder(folderPath, resolvedRoot, oldName); err != nil {
+		item.Status = PlanUnsafe
+		item.Detail = fmt.Sprintf("原文件名: %v", err)
+	} else if _, err := checkPathInFolder(targetFolder, resolvedTarget, newName); op != OpDelete && err != nil {
+		item.Status = PlanUnsafe
+		item.Detail = fmt.Sprintf("新文件名: %v", err)
+	} else {
+		// 移动到以分隔符结尾或已存在的文件夹时保留原文件名
+		intoFolder := false
+		if op == OpMove {
+			info, err := os.Stat(filepath.Join(targetFolder, newName))
+			intoFolder = strings.HasSuffix(newName, string(filepath.Separator)) || err == nil && info.IsDir()
+		}
+		item.OldName, item.NewName = filepath.Clean(oldName), filepath.Clean(newName)
+		if op == OpDelete {
+			item.NewName = ""
+		}
+		if options.Match != MatchExact {
+			if p.matcher == nil {
+				p.matcher = newNameMatcher(folderPath, options.Match)
+			}
+			matchSource(&item, folderPath, p.matcher)
+		}
+		if options.Recursive && item.Status == PlanOK {
+			if p.index, err = locateInSubfolders(&item, folderPath, p.index, options.Match); err != nil {
+				return err
+			}
+		}
+		if intoFolder {
+			item.NewName = filepath.Join(item.NewName, filepath.Base(item.OldName))
+		}
+		item.NewPath = ""
+		if op != OpDelete {
+			item.NewPath = filepath.Join(targetFolder, item.NewName)
+		}
+	}
+
+	// 同一个原文件可以复制出多个副本，但只能被一行重命名、移动或删除
+	if item.Status == PlanOK && plan.vacates(item) {
+		if j, ok := p.sources[item.OldPath]; ok {
+			item.Status = PlanDuplicateSource
+			item.Detail = fmt.Sprintf("与第 %d 行的原文件名相同", plan.Items[j].Row)
+		} else {
+			p.sources[item.OldPath] = i
+		}
+	}
+	plan.Items = append(plan.Items, item)
+	return nil
+}
+
+// finish 所有行加入后检查原文件、目标重复、链式依赖和冲突，返回完成的计划
+func (p *renamePlanner) finish() (*RenamePlan, error) {
+	plan, options := p.plan, p.plan.Options
+	sources, targetFolder := p.sources, p.targetFolder
+
+	// 第一轮：检查原文件，收集仍需处理的行的目标路径
+	targets := make(map[string][]int) // 新路径 -> 所有使用该路径的下标
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		if item.Status != PlanOK {
+			continue
+		}
+		if item.Op == OpMkdir {
+			if info, err := os.Lstat(item.NewPath); err == nil {
+				if info.IsDir() {
+					item.Status = PlanUnchanged
+					item.Detail = "文件夹已存在"
+				} else {
+					item.Status = PlanTargetExists
+					item.Detail = "已存在同名文件"
+				}
+				continue
+			}
+			targets[item.NewPath] = append(targets[item.NewPath], i)
+			continue
+		}
+		if item.OldPath == item.NewPath {
+			item.Status = PlanUnchanged
+			continue
+		}
+		if _, err := os.Lstat(item.OldPath); err != nil {
+			item.Status = PlanSourceMissing
+			if !os.IsNotExist(err) {
+				item.Detail = err.Error()
+			}
+			continue
+		}
+		if item.Op != OpDelete {
+			targets[item.NewPath] = append(targets[item.NewPath], i)
+		}
+	}
+
+	// 第二轮：检查目标是否重复、是否依赖其他行、是否已存在，链式依赖先标记为待定；
+	// 新建文件夹和复制在重命名之前执行，不能等待其他行腾出目标
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		if item.Status != PlanOK || item.Op == OpDelete {
+			continue
+		}
+		if rows := targets[item.NewPath]; len(rows) > 1 {
+			// 覆盖或追加序号时，第一行照常处理，其余行按设置处理；跳过和整批中止时所有重复行都不执行
+			keepFirst := options.Collision == CollisionOverwrite || options.Collision == CollisionSuffix
+			if !keepFirst || rows[0] != i {
+				resolveCollision(item, options.Collision, PlanDuplicateTarget, "与第 "+joinRows(plan.Items, rows, i)+" 行的新文件名相同")
+				if item.Status == PlanOverwrite && overwriteOutOfOrder(plan, rows, i, targets) {
+					item.Status = PlanDuplicateTarget
+					item.Detail += "，该行可能先于前面的行执行，无法保证按表格顺序覆盖，未执行"
+				}
+				continue
+			}
+		}
+		if j, ok := sources[item.NewPath]; ok && j != i && plan.vacates(*item) {
+			item.Status = PlanChain
+			continue
+		}
+		if targetOccupied(item.OldPath, item.NewPath) {
+			resolveCollision(item, options.Collision, PlanTargetExists, "目标文件已存在")
+		}
+	}
+
+	// 第三轮：被依赖的行无法执行时，目标文件不会被腾出，链式依赖随之失效
+	dependents := make(map[int][]int)
+	for i, item := range plan.Items {
+		if item.Status == PlanChain {
+			j := sources[item.NewPath]
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+	var queue []int
+	kept := make(map[int]bool)
+	for j, item := range plan.Items {
+		if !item.Runnable() {
+			queue = append(queue, j)
+		}
+	}
+	for len(queue) > 0 {
+		dep := plan.Items[queue[0]]
+		for _, i := range dependents[queue[0]] {
+			item := &plan.Items[i]
+			if item.Status != PlanChain {
+				continue
+			}
+			if targetOccupied(item.OldPath, item.NewPath) {
+				// 因重复目标未执行的行，其原文件需要保留，等待它的行（以及再等待这些行的行）不能覆盖
+				policy := options.Collision
+				if dep.Status == PlanDuplicateTarget || kept[queue[0]] {
+					policy = CollisionSkip
+					kept[i] = true
+				}
+				resolveCollision(item, policy, PlanTargetExists, fmt.Sprintf("第 %d 行%s，目标不会被腾出", dep.Row, dep.Status))
+				if !item.Runnable() {
+					queue = append(queue, i)
+				}
+			} else {
+				item.Status = PlanOK
+			}
+		}
+		queue = queue[1:]
+	}
+
+	// 第四轮：为需要追加序号的行分配不冲突的新文件名
+	assignSuffixes(plan, sources)
+
+	// 第五轮：补充链式依赖的说明，识别循环重命名
+	cycles := findRenameCycles(plan.Items, sources)
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		if item.Status != PlanChain {
+			continue
+		}
+		if size, ok := cycles[i]; ok {
+			item.Detail = fmt.Sprintf("循环重命名（共 %d 行），需等第 %d 行先重命名", size, plan.Items[sources[item.NewPath]].Row)
+		} else {
+			item.Detail = fmt.Sprintf("需等第 %d 行先重命名", plan.Items[sources[item.NewPath]].Row)
+		}
+	}
+
+	// 第六轮：标出需要自动创建的目标子文件夹，由新建文件夹的行创建的不再重复提示
+	made := make(map[string]bool)
+	for _, item := range plan.Items {
+		if item.Runnable() && item.Op == OpMkdir {
+			made[item.NewPath] = true
+		}
+	}
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		if !item.Runnable() || item.Op == OpDelete || item.Op == OpMkdir {
+			continue
+		}
+		dir := filepath.Dir(item.NewPath)
+		if _, err := os.Stat(dir); os.IsNotExist(err) && !made[dir] {
+			rel, _ := filepath.Rel(targetFolder, dir)
+			if rel == "." {
+				rel = dir
+			}
+			if item.Detail != "" {
+				item.Detail += "；"
+			}
+			item.Detail += "将创建文件夹 " + rel
+		}
+	}
+
+	return plan, nil
+}
+
+// locateInSubfolders 原文件不在表格给出的位置时，按匹配方式在所有子文件夹中查找同名文件；
+// 唯一匹配时改用找到的路径，重命名和复制的新文件名只是文件名时放在原文件所在的子文件夹中，
+// 匹配到多个文件时标记为不唯一
+func locateInSubfolders(item *PlanItem, folderPath string, index *fileIndex, mode MatchMode) (*fileIndex, error) {
+	if _, err := os.Lstat(item.OldPath); !os.IsNotExist(err) {
+		return index, nil
+	}
+	if index == nil {
+		var err error
+		if index, err = buildFileIndex(folderPath, mode); err != nil {
+			return nil, err
+		}
+	}
+
+	switch matches := index.find(item.OldName); len(matches) {
+	case 0:
+		// 保持原样，后续报告原文件不存在
+	case 1:
+		if item.Op != OpMove && item.NewName != "" && !strings.ContainsRune(item.NewName, filepath.Separator) {
+			item.NewName = filepath.Join(filepath.Dir(matches[0]), item.NewName)
+		}
+		useMatch(item, folderPath, matches[0])
+		item.Detail = "在子文件夹中找到原文件"
+	default:
+		item.Status = PlanAmbiguous
+		item.Detail = "匹配到多个文件: " + describeMatches(matches)
+	}
+	return index, nil
+}
+
+// overwriteOutOfOrder 判断覆盖模式下新文件名重复的后一行是否可能先于前面的行执行：
+// 其他行要等该行腾出原文件名时，该行会随链式或循环重命名提前执行；
+// 新建文件夹和复制在重命名之前执行。后一行先占用目标时，前面的行反而会因目标已存在而失败
+func overwriteOutOfOrder(plan *RenamePlan, rows []int, i int, targets map[string][]int) bool {
+	item := plan.Items[i]
+	if plan.vacates(item) && len(targets[item.OldPath]) > 0 {
+		return true
+	}
+	phase := func(item PlanItem) int {
+		switch {
+		case item.Op == OpMkdir:
+			return 0
+		case plan.copies(item):
+			return 1
+		}
+		return 2
+	}
+	for _, j := range rows {
+		if j < i && plan.Items[j].Runnable() && phase(plan.Items[j]) > phase(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveCollision 按冲突处理方式设置冲突行的状态，跳过和整批中止时保留冲突状态
+func resolveCollision(item *PlanItem, policy CollisionPolicy, conflict PlanStatus, detail string) {
+	item.Detail = detail
+	switch policy {
+	case CollisionOverwrite:
+		item.Status = PlanOverwrite
+	case CollisionSuffix:
+		item.Status = PlanAutoSuffix
+	default:
+		item.Status = conflict
+	}
+}
+
+// assignSuffixes 为追加序号的行生成形如“名称 (1).扩展名”的新文件名，
+// 新文件名既不能已存在于磁盘，也不能与其他行的原文件名或新文件名相同
+func assignSuffixes(plan *RenamePlan, sources map[string]int) {
+	reserved := make(map[string]bool)
+	for _, item := range plan.Items {
+		if item.Runnable() && item.Status != PlanAutoSuffix {
+			reserved[item.NewPath] = true
+		}
+	}
+
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		if item.Status != PlanAutoSuffix {
+			continue
+		}
+		ext := filepath.Ext(item.NewName)
+		stem := strings.TrimSuffix(item.NewName, ext)
+		for n := 1; ; n++ {
+			name := fmt.Sprintf("%s (%d)%s", stem, n, ext)
+			path := filepath.Join(plan.TargetFolder(), name)
+			if _, isSource := sources[path]; isSource || reserved[path] {
+				continue
+			}
+			if _, err := os.Lstat(path); err == nil {
+				continue
+			}
+			item.Detail = fmt.Sprintf("%s，改为 %s", item.Detail, name)
+			item.NewName = name
+			item.NewPath = path
+			reserved[path] = true
+			break
+		}
+	}
+}
+
+// targetOccupied 判断目标路径是否已被其他文件占用，
+// 在不区分大小写的文件系统上只改大小写时目标即原文件本身，不算占用
+func targetOccupied(oldPath, newPath string) bool {
+	newInfo, err := os.Lstat(newPath)
+	if err != nil {
+		return false
+	}
+	oldInfo, err := os.Lstat(oldPath)
+	if err != nil {
+		return true
+	}
+	return !os.SameFile(oldInfo, newInfo)
+}
+
+// findRenameCycles 沿链式依赖查找首尾相接的循环，返回循环中每一行的下标及循环长度
+func findRenameCycles(items []PlanItem, sources map[string]int) map[int]int {
+	cycles := make(map[int]int)
+	walk := make([]int, len(items)) // 0 表示未访问，否则为访问时的轮次
+	for start := range items {
+		if items[start].Status != PlanChain || walk[start] != 0 {
+			continue
+		}
+		var path []int
+		i := start
+		for {
+			walk[i] = start + 1
+			path = append(path, i)
+			j, ok := sources[items[i].NewPath]
+			if !ok || items[j].Status != PlanChain {
+				break
+			}
+			if walk[j] == start+1 {
+				// 从 j 开始的部分构成循环
+				for k := len(path) - 1; k >= 0; k-- {
+					if path[k] == j {
+						for _, member := range path[k:] {
+							cycles[member] = len(path) - k
+						}
+						break
+					}
+				}
+				break
+			}
+			if walk[j] != 0 {
+				break
+			}
+			i = j
+		}
+	}
+	return cycles
+}
+
+// joinRows 拼接除自身以外的行号
+func joinRows(items []PlanItem, indexes []int, self int) string {
+	var rows []string
+	for _, i := range indexes {
+		if i != self {
+			rows = append(rows, fmt.Sprint(items[i].Row))
+		}
+	}
+	return strings.Join(rows, "、")
+}
@@ -0,0 +1,43 @@
+package utils
+
+import "testing"
+
+func TestPlanRenameRecursiveFindsFilesInSubfolders(t *testing.T) {
+	files := map[string]string{
+		"章节1/音频/001.mp3": "1-1",
+		"章节1/音频/002.mp3": "1-2",
+		"章节2/音频/001.mp3": "2-1",
+		"root.mp3":       "r",
+	}
+	rows := []ExcelData{
+		{OldName: "002.mp3", NewName: "b.mp3"},
+		{OldName: "001.mp3", NewName: "a.mp3"},
+		{OldName: "章节2/音频/001.mp3", NewName: "章节2/c.mp3"},
+		{OldName: "root.mp3", NewName: "r.mp3"},
+	}
+	plan, results, dir := runTestPlan(t, files, rows, RenameOptions{Recursive: true})
+
+	want := []PlanStatus{PlanOK, PlanAmbiguous, PlanOK, PlanOK}
+	for i, item := range plan.Items {
+		if item.Status != want[i] {
+			t.Errorf("第 %d 行计划状态为 %s（%s），应为 %s", item.Row, item.Status, item.Detail, want[i])
+		}
+	}
+	checkStatuses(t, results, RenameSucceeded, RenameFailed, RenameSucceeded, RenameSucceeded)
+	// 只写文件名的新文件名放在找到的原文件所在的子文件夹中
+	checkTestFiles(t, dir, map[string]string{
+		"章节1/音频/b.mp3":   "1-2",
+		"章节1/音频/001.mp3": "1-1",
+		"章节2/c.mp3":      "2-1",
+		"r.mp3":          "r",
+	})
+
+	journal, err := LastRenameJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UndoRename(journal); err != nil {
+		t.Fatal(err)
+	}
+	checkTestFiles(t, dir, files)
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ResultSheetName 写回源表格时使用的工作表名称
+const ResultSheetName = "结果"
+
+// renameReportHeader 重命名结果表格的标题行
+var renameReportHeader = []interface{}{"行号", "操作", "原路径", "新路径", "结果", "错误类型", "说明", "匹配说明"}
+
+// WriteRenameResultSheet 把重命名结果写入源表格中的“结果”工作表，已有的同名工作表会被替换
+func WriteRenameResultSheet(workbookPath string, results []RenameResult) error {
+	f, err := excelize.OpenFile(workbookPath)
+	if err != nil {
+		return fmt.Errorf("打开Excel文件失败: %v", err)
+	}
+	defer f.Close()
+
+	if index, _ := f.GetSheetIndex(ResultSheetName); index >= 0 {
+		if err := f.DeleteSheet(ResultSheetName); err != nil {
+			return fmt.Errorf("删除旧的结果工作表失败: %v", err)
+		}
+	}
+	if _, err := f.NewSheet(ResultSheetName); err != nil {
+		return fmt.Errorf("创建结果工作表失败: %v", err)
+	}
+	if err := writeRenameResults(f, ResultSheetName, results); err != nil {
+		return err
+	}
+	if err := f.Save(); err != nil {
+		return fmt.Errorf("保存Excel文件失败: %v", err)
+	}
+	return nil
+}
+
+// ExportRenameReport 把重命名结果另存为单独的报告表格
+func ExportRenameReport(reportPath string, results []RenameResult) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName(f.GetSheetName(0), ResultSheetName); err != nil {
+		return fmt.Errorf("创建结果工作表失败: %v", err)
+	}
+	if err := writeRenameResults(f, ResultSheetName, results); err != nil {
+		return err
+	}
+	if err := f.SaveAs(reportPath); err != nil {
+		return fmt.Errorf("保存报告失败: %v", err)
+	}
+	return nil
+}
+
+// writeRenameResults 把结果逐行写入指定的空工作表，并为标题行加上筛选
+func writeRenameResults(f *excelize.File, sheet string, results []RenameResult) error {
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return fmt.Errorf("写入结果工作表失败: %v", err)
+	}
+	sw.SetColWidth(1, 1, 8)
+	sw.SetColWidth(2, 2, 12)
+	sw.SetColWidth(3, 4, 50)
+	sw.SetColWidth(5, 6, 12)
+	sw.SetColWidth(7, 7, 60)
+	sw.SetColWidth(8, 8, 40)
+	sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+
+	if err := sw.SetRow("A1", renameReportHeader); err != nil {
+		return fmt.Errorf("写入结果工作表失败: %v", err)
+	}
+	for i, result := range results {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		row := []interface{}{result.Row, result.Op.String(), result.OldPath, result.NewPath, result.Status.String(), result.Kind.String(), result.Detail, result.Match}
+		if err := sw.SetRow(cell, row); err != nil {
+			return fmt.Errorf("写入结果工作表失败: %v", err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		return fmt.Errorf("写入结果工作表失败: %v", err)
+	}
+
+	// 给标题行加上自动筛选，方便按结果或错误类型筛出失败的行
+	lastCell, _ := excelize.CoordinatesToCellName(len(renameReportHeader), len(results)+1)
+	if err := f.AutoFilter(sheet, "A1:"+lastCell, nil); err != nil {
+		return fmt.Errorf("设置筛选失败: %v", err)
+	}
+	return nil
+}
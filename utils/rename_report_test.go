@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWriteRenameResultSheet(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"a": "a"})
+	workbook := filepath.Join(t.TempDir(), "rename.xlsx")
+	f := excelize.NewFile()
+	f.SetSheetRow("Sheet1", "A1", &[]interface{}{"原文件名", "新文件名"})
+	f.SetSheetRow("Sheet1", "A2", &[]interface{}{"a", "x"})
+	f.SetSheetRow("Sheet1", "A3", &[]interface{}{"missing", "y"})
+	if err := f.SaveAs(workbook); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ReadExcelForRename(workbook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := RenameFilesWithOptions(dir, data, RenameOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 再次写入时替换已有的结果工作表
+	for i := 0; i < 2; i++ {
+		if err := WriteRenameResultSheet(workbook, results); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, err := excelize.OpenFile(workbook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	if sheets := g.GetSheetList(); len(sheets) != 2 || sheets[1] != ResultSheetName {
+		t.Fatalf("工作表为 %v，应为原工作表和一个结果工作表", sheets)
+	}
+	rows, err := g.GetRows(ResultSheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("结果工作表有 %d 行，应为标题和 2 行结果", len(rows))
+	}
+	checkResultRows(t, g, [][]string{{"2", "成功", ""}, {"3", "失败", "原文件不存在"}})
+
+	// 写回结果后源表格仍按第一个工作表读取
+	again, err := ReadExcelForRename(workbook)
+	if err != nil || len(again) != 2 {
+		t.Fatalf("写回结果后读取源表格得到 %v, %v", again, err)
+	}
+}
+
+func TestExportRenameReport(t *testing.T) {
+	results := []RenameResult{
+		{Row: 2, Op: OpRename, OldPath: "a", NewPath: "x", Status: RenameSucceeded},
+		{Row: 3, Op: OpRename, OldPath: "b", NewPath: "x", Status: RenameSkipped, Kind: KindDuplicate, Detail: "重复"},
+	}
+	report := filepath.Join(t.TempDir(), "report.xlsx")
+	if err := ExportRenameReport(report, results); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if sheets := f.GetSheetList(); len(sheets) != 1 || sheets[0] != ResultSheetName {
+		t.Fatalf("工作表为 %v，应只有结果工作表", sheets)
+	}
+	checkResultRows(t, f, [][]string{{"2", RenameSucceeded.String(), ""}, {"3", RenameSkipped.String(), KindDuplicate.String()}})
+}
+
+// checkResultRows 检查结果工作表每行的行号、结果和错误类型
+func checkResultRows(t *testing.T, f *excelize.File, want [][]string) {
+	t.Helper()
+	for i, w := range want {
+		var got []string
+		for _, col := range []string{"A", "E", "F"} {
+			value, err := f.GetCellValue(ResultSheetName, fmt.Sprintf("%s%d", col, i+2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, value)
+		}
+		if got[0] != w[0] || got[1] != w[1] || got[2] != w[2] {
+			t.Errorf("第 %d 行为 %v，应为 %v", i+2, got, w)
+		}
+	}
+}
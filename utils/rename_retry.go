@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// DefaultRenameRetries 界面和监视模式默认的重试次数，按加倍的等待时间共等待约 3.5 秒
+const DefaultRenameRetries = 3
+
+// 重试前的等待时间，每次加倍，不超过上限
+var (
+	renameRetryDelay    = 500 * time.Millisecond
+	renameRetryMaxDelay = 8 * time.Second
+)
+
+// retryBusy 执行一行的文件操作，文件被其他进程占用时等待后重试，最多重试 retries 次；
+// 返回实际重试的次数，仍然失败时返回最后一次的错误
+func retryBusy(retries int, op func() error) (int, error) {
+	delay := renameRetryDelay
+	err := op()
+	for retried := 0; ; retried++ {
+		if err == nil || retried >= retries || !isBusy(err) {
+			return retried, err
+		}
+		time.Sleep(delay)
+		if delay *= 2; delay > renameRetryMaxDelay {
+			delay = renameRetryMaxDelay
+		}
+		err = op()
+	}
+}
+
+// retryNote 返回重试后成功时的说明，没有重试时为空
+func retryNote(retried int) string {
+	if retried == 0 {
+		return ""
+	}
+	return fmt.Sprintf("文件被占用，重试 %d 次后成功", retried)
+}
+
+// retryable 判断上次失败的行是否值得重新执行：执行时失败的行，以及原文件当时还不存在（如仍在同步）的行；
+// 文件名无效、路径不安全等重试也不会成功的行不包括在内
+func retryable(item PlanItem, result RenameResult) bool {
+	return result.Status == RenameFailed && (item.Runnable() || item.Status == PlanSourceMissing)
+}
+
+// RetryData 返回上次执行中可以重试的失败行，交给 PlanRename 后只会重新执行这些行。
+// 循环重命名中途失败、暂存在临时文件名上的文件，从临时文件名改为原定的新文件名
+func RetryData(plan *RenamePlan, results []RenameResult) []ExcelData {
+	var data []ExcelData
+	for i, item := range plan.Items {
+		if i >= len(results) || !retryable(item, results[i]) {
+			continue
+		}
+		oldName := item.OldName
+		if results[i].NewPath != item.NewPath && results[i].NewPath != "" {
+			if rel, err := filepath.Rel(plan.FolderPath, results[i].NewPath); err == nil {
+				oldName = rel
+			}
+		}
+		data = append(data, ExcelData{Row: item.Row, OldName: oldName, NewName: item.NewName, Operation: item.Op.String()})
+	}
+	return data
+}
+
+// PlanRetry 按上次的文件夹和选项，为上次执行中失败的行重新生成重命名计划
+func PlanRetry(plan *RenamePlan, results []RenameResult) (*RenamePlan, error) {
+	data := RetryData(plan, results)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("没有可以重试的失败行")
+	}
+	return PlanRename(plan.FolderPath, data, plan.Options)
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// busyError 返回当前系统上表示文件被占用的错误，Windows 上为共享冲突
+func busyError() error {
+	errno := syscall.EBUSY
+	if runtime.GOOS == "windows" {
+		errno = syscall.Errno(32)
+	}
+	return &fs.PathError{Op: "rename", Path: "a", Err: errno}
+}
+
+// useShortRetryDelay 缩短重试的等待时间，测试结束后恢复
+func useShortRetryDelay(t *testing.T) {
+	t.Helper()
+	delay, maxDelay := renameRetryDelay, renameRetryMaxDelay
+	renameRetryDelay, renameRetryMaxDelay = time.Millisecond, 2*time.Millisecond
+	t.Cleanup(func() { renameRetryDelay, renameRetryMaxDelay = delay, maxDelay })
+}
+
+func TestRetryBusy(t *testing.T) {
+	useShortRetryDelay(t)
+	busy := busyError()
+	if kind := classifyRenameError(fmt.Errorf("%w（已重试 2 次）", busy)); kind != KindBusy {
+		t.Fatalf("错误类型为 %s，应为 %s", kind, KindBusy)
+	}
+
+	// failures 为前几次调用返回的错误，之后的调用成功
+	tests := []struct {
+		name        string
+		retries     int
+		failures    []error
+		wantRetried int
+		wantCalls   int
+		wantErr     error
+	}{
+		{"占用解除后成功", 3, []error{busy, busy}, 2, 3, nil},
+		{"超过重试次数", 2, []error{busy, busy, busy}, 2, 3, busy},
+		{"不重试其他错误", 5, []error{fs.ErrPermission}, 0, 1, fs.ErrPermission},
+		{"不允许重试", 0, []error{busy}, 0, 1, busy},
+	}
+	for _, tt := range tests {
+		calls := 0
+		retried, err := retryBusy(tt.retries, func() error {
+			calls++
+			if calls <= len(tt.failures) {
+				return tt.failures[calls-1]
+			}
+			return nil
+		})
+		if retried != tt.wantRetried || calls != tt.wantCalls || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: 重试 %d 次、调用 %d 次、错误 %v，应为重试 %d 次、调用 %d 次、错误 %v",
+				tt.name, retried, calls, err, tt.wantRetried, tt.wantCalls, tt.wantErr)
+		}
+	}
+	if note := retryNote(2); note != "文件被占用，重试 2 次后成功" {
+		t.Errorf("重试说明为 %q", note)
+	}
+	if note := retryNote(0); note != "" {
+		t.Errorf("没有重试时说明为 %q，应为空", note)
+	}
+}
+
+func TestPlanRetryRerunsOnlyFailedRows(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"a.txt": "a", "b.txt": "b"})
+	rows := []ExcelData{
+		{Row: 2, OldName: "a.txt", NewName: "A.txt"},
+		{Row: 3, OldName: "b.txt", NewName: "B.txt"},
+		{Row: 4, OldName: "c.txt", NewName: "C.txt"},
+		{Row: 5, OldName: "d.txt", NewName: "D.txt", Problem: "无法生成新文件名"},
+	}
+	plan, err := PlanRename(dir, rows, RenameOptions{Retries: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 执行前 B.txt 被占用，c.txt 还没有同步过来
+	writeTestFiles(t, dir, map[string]string{"B.txt": "late"})
+	results, err := ExecuteRenamePlan(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	retry := RetryData(plan, results)
+	if len(retry) != 2 || retry[0].OldName != "b.txt" || retry[1].OldName != "c.txt" {
+		t.Fatalf("可重试的行为 %+v，应为 b.txt 和 c.txt", retry)
+	}
+	if err := os.Remove(filepath.Join(dir, "B.txt")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, dir, map[string]string{"c.txt": "c"})
+	retryPlan, err := PlanRetry(plan, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := retryPlan.RunnableCount(); n != 2 {
+		t.Fatalf("重试计划有 %d 行可执行，应为 2 行", n)
+	}
+	results, err = ExecuteRenamePlan(retryPlan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStatuses(t, results, RenameSucceeded, RenameSucceeded)
+	checkTestFiles(t, dir, map[string]string{"A.txt": "a", "B.txt": "b", "C.txt": "c"})
+	if _, err := PlanRetry(retryPlan, results); err == nil {
+		t.Error("全部成功后不应再有可重试的行")
+	}
+}
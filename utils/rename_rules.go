@@ -0,0 +1,359 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// RuleType 重命名规则类型
+type RuleType string
+
+const (
+	RuleReplace RuleType = "replace" // 文本替换，可使用正则表达式
+	RulePrefix  RuleType = "prefix"  // 添加前缀
+	RuleSuffix  RuleType = "suffix"  // 添加后缀（在扩展名之前）
+	RuleCase    RuleType = "case"    // 转换大小写
+	RuleNumber  RuleType = "number"  // 按自然排序编号
+	RuleScript  RuleType = "script"  // 文字转换：汉字转拼音、简繁转换或转为拉丁字母
+)
+
+// 大小写转换方式
+const (
+	CaseUpper = "upper" // 全部大写
+	CaseLower = "lower" // 全部小写
+	CaseTitle = "title" // 单词首字母大写
+)
+
+// 序号的位置
+const (
+	NumberPrefix  = "prefix"  // 放在文件名前面
+	NumberSuffix  = "suffix"  // 放在文件名后面
+	NumberReplace = "replace" // 替换整个文件名
+)
+
+// RenameRule 一条重命名规则，默认只作用于不含扩展名的部分
+type RenameRule struct {
+	Type      RuleType `json:"type"`
+	Find      string   `json:"find,omitempty"`      // 文本替换：查找的内容
+	Replace   string   `json:"replace,omitempty"`   // 文本替换：替换为，正则模式下可用 $1 引用分组
+	Regex     bool     `json:"regex,omitempty"`     // 文本替换：查找内容是否为正则表达式
+	Text      string   `json:"text,omitempty"`      // 前缀或后缀的文本
+	Case      string   `json:"case,omitempty"`      // 大小写转换方式
+	Start     int      `json:"start,omitempty"`     // 序号：起始值
+	Step      int      `json:"step,omitempty"`      // 序号：步长，0 按 1 处理
+	Width     int      `json:"width,omitempty"`     // 序号：位数，不足时补 0
+	Separator string   `json:"separator,omitempty"` // 序号与文件名之间的分隔符；文字转换时为相邻汉字的拼音之间的分隔符
+	Position  string   `json:"position,omitempty"`  // 序号的位置
+	Extension bool     `json:"extension,omitempty"` // 文本替换、大小写转换和文字转换是否同时作用于扩展名
+
+	Script ScriptConversion `json:"script,omitempty"` // 文字转换方式
+}
+
+// Describe 返回规则的中文说明
+func (r RenameRule) Describe() string {
+	scope := ""
+	if r.Extension {
+		scope = "（含扩展名）"
+	}
+	switch r.Type {
+	case RuleReplace:
+		if r.Regex {
+			return fmt.Sprintf("正则替换%s: %s → %s", scope, r.Find, r.Replace)
+		}
+		return fmt.Sprintf("文本替换%s: %s → %s", scope, r.Find, r.Replace)
+	case RulePrefix:
+		return "添加前缀: " + r.Text
+	case RuleSuffix:
+		return "添加后缀: " + r.Text
+	case RuleCase:
+		names := map[string]string{CaseUpper: "全部大写", CaseLower: "全部小写", CaseTitle: "首字母大写"}
+		return "大小写" + scope + ": " + names[r.Case]
+	case RuleNumber:
+		positions := map[string]string{NumberPrefix: "放在前面", NumberSuffix: "放在后面", NumberReplace: "替换文件名"}
+		return fmt.Sprintf("编号: 从 %d 开始，步长 %d，%d 位，%s", r.Start, r.step(), r.Width, positions[r.Position])
+	case RuleScript:
+		if r.Separator != "" && r.Script != ConvertToSimplified && r.Script != ConvertToTraditional {
+			return fmt.Sprintf("文字转换%s: %s，分隔符 %s", scope, r.Script, r.Separator)
+		}
+		return fmt.Sprintf("文字转换%s: %s", scope, r.Script)
+	}
+	return string(r.Type)
+}
+
+// step 返回序号步长，未设置时为 1
+func (r RenameRule) step() int {
+	if r.Step == 0 {
+		return 1
+	}
+	return r.Step
+}
+
+// RuleChain 规则链，按顺序依次应用到文件夹中的每个文件
+type RuleChain struct {
+	Rules  []RenameRule `json:"rules"`
+	Filter string       `json:"filter,omitempty"` // 只处理匹配的文件，如 *.mp3，为空时处理全部文件
+}
+
+// Validate 检查规则链中的每条规则和文件筛选条件是否有效
+func (c RuleChain) Validate() error {
+	_, err := c.compile()
+	return err
+}
+
+// compile 检查规则并预编译正则表达式
+func (c RuleChain) compile() ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, len(c.Rules))
+	for i, rule := range c.Rules {
+		switch rule.Type {
+		case RuleReplace:
+			if rule.Find == "" {
+				return nil, fmt.Errorf("第 %d 条规则: 查找内容不能为空", i+1)
+			}
+			if rule.Regex {
+				pattern, err := regexp.Compile(rule.Find)
+				if err != nil {
+					return nil, fmt.Errorf("第 %d 条规则: 正则表达式无效: %v", i+1, err)
+				}
+				patterns[i] = pattern
+			}
+		case RuleCase:
+			if rule.Case != CaseUpper && rule.Case != CaseLower && rule.Case != CaseTitle {
+				return nil, fmt.Errorf("第 %d 条规则: 未知的大小写转换方式: %s", i+1, rule.Case)
+			}
+		case RuleNumber:
+			if rule.Width < 0 {
+				return nil, fmt.Errorf("第 %d 条规则: 序号位数不能为负数", i+1)
+			}
+		case RuleScript:
+			if err := rule.Script.Validate(); err != nil {
+				return nil, fmt.Errorf("第 %d 条规则: %v", i+1, err)
+			}
+		case RulePrefix, RuleSuffix:
+		default:
+			return nil, fmt.Errorf("第 %d 条规则: 未知的规则类型: %s", i+1, rule.Type)
+		}
+	}
+	if c.Filter != "" {
+		if _, err := filepath.Match(c.Filter, ""); err != nil {
+			return nil, fmt.Errorf("文件筛选条件无效: %v", err)
+		}
+	}
+	return patterns, nil
+}
+
+// Apply 把规则链应用到一组文件名上，index 为每个文件在自然排序中的位置，用于编号
+func (c RuleChain) Apply(names []string) ([]string, error) {
+	return c.applyFrom(names, 0)
+}
+
+// numbered 判断规则链中是否有编号规则
+func (c RuleChain) numbered() bool {
+	for _, rule := range c.Rules {
+		if rule.Type == RuleNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFrom 与 Apply 相同，编号从第 first 个文件开始计算，用于分批到达的文件接着编号
+func (c RuleChain) applyFrom(names []string, first int) ([]string, error) {
+	patterns, err := c.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, len(names))
+	for index, name := range names {
+		for i, rule := range c.Rules {
+			name = applyRule(rule, patterns[i], name, first+index)
+		}
+		result[index] = name
+	}
+	return result, nil
+}
+
+// applyRule 对单个文件名应用一条规则
+func applyRule(rule RenameRule, pattern *regexp.Regexp, name string, index int) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	if rule.Extension {
+		stem, ext = name, ""
+	}
+
+	switch rule.Type {
+	case RuleReplace:
+		if pattern != nil {
+			stem = pattern.ReplaceAllString(stem, rule.Replace)
+		} else {
+			stem = strings.ReplaceAll(stem, rule.Find, rule.Replace)
+		}
+	case RulePrefix:
+		stem = rule.Text + stem
+	case RuleSuffix:
+		stem = stem + rule.Text
+	case RuleCase:
+		switch rule.Case {
+		case CaseUpper:
+			stem = strings.ToUpper(stem)
+		case CaseLower:
+			stem = strings.ToLower(stem)
+		case CaseTitle:
+			stem = titleCase(stem)
+		}
+	case RuleNumber:
+		number := fmt.Sprintf("%0*d", rule.Width, rule.Start+index*rule.step())
+		switch rule.Position {
+		case NumberSuffix:
+			stem = stem + rule.Separator + number
+		case NumberReplace:
+			stem = number
+		default:
+			stem = number + rule.Separator + stem
+		}
+	case RuleScript:
+		stem = ConvertScript(stem, rule.Script, rule.Separator)
+	}
+	return stem + ext
+}
+
+// titleCase 把每个单词的首字母转为大写，空格、下划线、连字符和点视为单词分隔
+func titleCase(s string) string {
+	runes := []rune(s)
+	start := true
+	for i, r := range runes {
+		if r == ' ' || r == '_' || r == '-' || r == '.' {
+			start = true
+			continue
+		}
+		if start {
+			runes[i] = unicode.ToUpper(r)
+		} else {
+			runes[i] = unicode.ToLower(r)
+		}
+		start = false
+	}
+	return string(runes)
+}
+
+// ListFolderFiles 列出文件夹中的文件（不含子文件夹和隐藏文件），按自然顺序排列
+func ListFolderFiles(folderPath, filter string) ([]string, error) {
+	entries, err := os.ReadDir(folderPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件夹失败: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if filter != "" {
+			if ok, _ := filepath.Match(filter, entry.Name()); !ok {
+				continue
+			}
+		}
+		names = append(names, entry.Name())
+	}
+	SortNatural(names)
+	return names, nil
+}
+
+// BuildRuleRenames 按规则链为文件夹中的文件生成新旧文件名对照，结果可直接交给 PlanRename
+func BuildRuleRenames(folderPath string, chain RuleChain) ([]ExcelData, error) {
+	if err := chain.Validate(); err != nil {
+		return nil, err
+	}
+	names, err := ListFolderFiles(folderPath, chain.Filter)
+	if err != nil {
+		return nil, err
+	}
+	newNames, err := chain.Apply(names)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]ExcelData, len(names))
+	for i, name := range names {
+		data[i] = ExcelData{Row: i + 1, OldName: name, NewName: newNames[i]}
+	}
+	return data, nil
+}
+
+// SaveRuleChain 把规则链保存为 JSON 文件，便于重复使用
+func SaveRuleChain(path string, chain RuleChain) error {
+	content, err := json.MarshalIndent(chain, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化规则失败: %v", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("保存规则失败: %v", err)
+	}
+	return nil
+}
+
+// LoadRuleChain 从 JSON 文件读取规则链
+func LoadRuleChain(path string) (RuleChain, error) {
+	var chain RuleChain
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return chain, fmt.Errorf("读取规则文件失败: %v", err)
+	}
+	if err := json.Unmarshal(content, &chain); err != nil {
+		return chain, fmt.Errorf("解析规则文件失败: %v", err)
+	}
+	if err := chain.Validate(); err != nil {
+		return chain, err
+	}
+	return chain, nil
+}
+
+// SortNatural 按自然顺序排序文件名，如 2.mp3 排在 10.mp3 之前
+func SortNatural(names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		return NaturalLess(names[i], names[j])
+	})
+}
+
+// NaturalLess 自然顺序比较：连续的数字按数值大小比较，其余字符不区分大小写比较
+func NaturalLess(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if unicode.IsDigit(ra[i]) && unicode.IsDigit(rb[j]) {
+			si := i
+			for i < len(ra) && unicode.IsDigit(ra[i]) {
+				i++
+			}
+			sj := j
+			for j < len(rb) && unicode.IsDigit(rb[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ra[si:i]), "0")
+			nb := strings.TrimLeft(string(rb[sj:j]), "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			continue
+		}
+		ca, cb := unicode.ToLower(ra[i]), unicode.ToLower(rb[j])
+		if ca != cb {
+			return ca < cb
+		}
+		i++
+		j++
+	}
+	if len(ra)-i != len(rb)-j {
+		return len(ra)-i < len(rb)-j
+	}
+	return a < b
+}
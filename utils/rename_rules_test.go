@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSortNatural(t *testing.T) {
+	names := []string{"10.mp3", "2.mp3", "a1.txt", "A02.txt", "1.mp3", "file001b", "file1a"}
+	SortNatural(names)
+	want := []string{"1.mp3", "2.mp3", "10.mp3", "a1.txt", "A02.txt", "file1a", "file001b"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("排序结果为 %v，应为 %v", names, want)
+	}
+}
+
+func TestBuildRuleRenamesAppliesChainInNaturalOrder(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"IMG 10.JPG": "10", "IMG 2.JPG": "2", "IMG 1.JPG": "1", "note.txt": "n", "sub/IMG 3.JPG": "3",
+	})
+	chain := RuleChain{Filter: "*.JPG", Rules: []RenameRule{
+		{Type: RuleReplace, Find: `IMG (\d+)`, Replace: "photo_$1", Regex: true},
+		{Type: RuleCase, Case: CaseLower, Extension: true},
+		{Type: RuleNumber, Start: 1, Width: 3, Separator: "_", Position: NumberPrefix},
+		{Type: RuleSuffix, Text: "-x"},
+	}}
+	data, err := BuildRuleRenames(dir, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, row := range data {
+		got = append(got, row.OldName+" -> "+row.NewName)
+	}
+	want := []string{"IMG 1.JPG -> 001_photo_1-x.jpg", "IMG 2.JPG -> 002_photo_2-x.jpg", "IMG 10.JPG -> 003_photo_10-x.jpg"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("改名结果为 %v，应为 %v", got, want)
+	}
+
+	plan, err := PlanRename(dir, data, RenameOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExecuteRenamePlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	checkTestFiles(t, dir, map[string]string{
+		"001_photo_1-x.jpg": "1", "002_photo_2-x.jpg": "2", "003_photo_10-x.jpg": "10", "note.txt": "n", "sub/IMG 3.JPG": "3",
+	})
+}
+
+func TestRuleChainValidateAndSave(t *testing.T) {
+	bad := RuleChain{Rules: []RenameRule{{Type: RuleReplace, Find: "(", Regex: true}}}
+	if err := bad.Validate(); err == nil {
+		t.Fatal("无效的正则表达式应报错")
+	}
+
+	chain := RuleChain{Filter: "*.mp3", Rules: []RenameRule{
+		{Type: RulePrefix, Text: "第"},
+		{Type: RuleNumber, Start: 5, Width: 2, Position: NumberSuffix},
+	}}
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := SaveRuleChain(path, chain); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadRuleChain(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, chain) {
+		t.Fatalf("读取的规则为 %+v，应为 %+v", loaded, chain)
+	}
+	names, err := loaded.Apply([]string{"a.mp3", "b.mp3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"第a05.mp3", "第b06.mp3"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("改名结果为 %v，应为 %v", names, want)
+	}
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CheckPathInFolder 检查表格中的文件名是否安全：不能为空、不能是绝对路径，
+// 拼接后不能通过 .. 或符号链接跑到目标文件夹之外，返回拼接后的完整路径
+func CheckPathInFolder(folderPath, name string) (string, error) {
+	resolvedRoot, err := filepath.EvalSymlinks(folderPath)
+	if err != nil {
+		return "", fmt.Errorf("解析目标文件夹失败: %v", err)
+	}
+	return checkPathInFolder(folderPath, resolvedRoot, name)
+}
+
+// checkPathInFolder 与 CheckPathInFolder 相同，目标文件夹的真实路径由调用方预先解析
+func checkPathInFolder(folderPath, resolvedRoot, name string) (string, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", fmt.Errorf("文件名为空")
+	}
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" || hasDriveLetter(name) || strings.HasPrefix(name, "/") || strings.HasPrefix(name, `\`) {
+		return "", fmt.Errorf("不能使用绝对路径: %s", name)
+	}
+
+	path := filepath.Join(folderPath, name)
+	if !insideFolder(filepath.Clean(folderPath), path) {
+		return "", fmt.Errorf("路径超出目标文件夹: %s", name)
+	}
+
+	// 文件本身是符号链接时重命名的是链接，只需确认其所在目录没有经符号链接跑出去
+	parent, err := resolveExisting(filepath.Dir(path))
+	if err != nil {
+		return "", fmt.Errorf("解析路径失败: %v", err)
+	}
+	if !insideFolder(resolvedRoot, filepath.Join(parent, filepath.Base(path))) {
+		return "", fmt.Errorf("路径经符号链接指向目标文件夹之外: %s", name)
+	}
+	return path, nil
+}
+
+// hasDriveLetter 判断文件名是否以 Windows 盘符开头，如 C:\x 或 C:x；
+// 表格常在 Windows 上编辑，在其他系统上同样按绝对路径拒绝
+func hasDriveLetter(name string) bool {
+	return len(name) >= 2 && name[1] == ':' && ('a' <= name[0] && name[0] <= 'z' || 'A' <= name[0] && name[0] <= 'Z')
+}
+
+// insideFolder 判断 path 是否位于 root 之内（不含 root 本身）
+func insideFolder(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveExisting 解析路径中已存在部分的符号链接，尚不存在的部分原样拼接在后面
+func resolveExisting(path string) (string, error) {
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+}
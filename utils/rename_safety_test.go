@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckPathInFolder(t *testing.T) {
+	root := t.TempDir()
+	folder := filepath.Join(root, "folder")
+	outside := filepath.Join(root, "outside")
+	writeTestFiles(t, root, map[string]string{"folder/a.txt": "a", "folder/sub/b.txt": "b", "outside/x": "x"})
+	symlinks := os.Symlink(outside, filepath.Join(folder, "out")) == nil &&
+		os.Symlink(outside, filepath.Join(folder, "sub", "out")) == nil &&
+		os.Symlink(filepath.Join(folder, "sub"), filepath.Join(folder, "in")) == nil
+
+	tests := []struct {
+		name    string
+		path    string
+		symlink bool // 需要创建符号链接
+		ok      bool
+	}{
+		{name: "普通文件名", path: "a.txt", ok: true},
+		{name: "子文件夹", path: "sub/b.txt", ok: true},
+		{name: "尚不存在的子文件夹", path: "new/dir/c.txt", ok: true},
+		{name: "在文件夹内部的 ..", path: "sub/../a.txt", ok: true},
+		{name: "空文件名", path: "  "},
+		{name: "文件夹本身", path: "."},
+		{name: "上级目录", path: "../x"},
+		{name: "上级目录中的文件夹", path: "../outside/x"},
+		{name: "绕回后跑出", path: "sub/../../outside/x"},
+		{name: "Unix 绝对路径", path: "/etc/passwd"},
+		{name: "反斜杠开头", path: `\Windows\x`},
+		{name: "Windows 盘符", path: `C:\Windows\x`},
+		{name: "Windows 盘符相对路径", path: "c:x"},
+		{name: "Windows 盘符正斜杠", path: "D:/x"},
+		{name: "UNC 路径", path: `\\server\share\x`},
+		{name: "正斜杠 UNC 路径", path: "//server/share/x"},
+		{name: "符号链接本身", path: "out", symlink: true, ok: true},
+		{name: "经符号链接跑出", path: "out/x", symlink: true},
+		{name: "经子文件夹中的符号链接跑出", path: "sub/out/x", symlink: true},
+		{name: "经符号链接新建到外部", path: "out/new/y", symlink: true},
+		{name: "指向内部的符号链接", path: "in/b.txt", symlink: true, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.symlink && !symlinks {
+				t.Skip("无法创建符号链接")
+			}
+			path, err := CheckPathInFolder(folder, tt.path)
+			if tt.ok && err != nil {
+				t.Fatalf("%q 应被接受: %v", tt.path, err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatalf("%q 应被拒绝，却返回 %s", tt.path, path)
+			}
+		})
+	}
+}
+
+func TestCheckPathInSymlinkedFolder(t *testing.T) {
+	// 目标文件夹本身经符号链接访问时，文件夹内的文件名仍应被接受
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{"real/a.txt": "a"})
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(filepath.Join(root, "real"), link); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+	if _, err := CheckPathInFolder(link, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckPathInFolder(link, "../real/a.txt"); err == nil {
+		t.Fatal("经 .. 跑出的路径应被拒绝")
+	}
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrFieldMissing 模板字段在当前文件中没有值，有候选字段时会继续尝试下一个
+var ErrFieldMissing = errors.New("字段没有值")
+
+// Template 文件名模板，由普通文本和 {字段:格式} 占位符组成。
+// 占位符可用 | 列出多个候选字段，如 {exif.DateTimeOriginal|mtime:2006-01-02}，
+// 依次取第一个有值的字段；{{ 和 }} 表示普通的大括号
+type Template struct {
+	Source string
+	parts  []templatePart
+}
+
+// templatePart 模板中的一段普通文本或一个占位符
+type templatePart struct {
+	text   string   // 普通文本
+	fields []string // 占位符的候选字段，为空表示普通文本
+	format string   // 占位符的格式，冒号之后的全部内容
+}
+
+// ParseTemplate 解析文件名模板
+func ParseTemplate(source string) (*Template, error) {
+	t := &Template{Source: source}
+	var text strings.Builder
+	for i := 0; i < len(source); i++ {
+		c := source[i]
+		switch {
+		case c == '{' && strings.HasPrefix(source[i:], "{{"), c == '}' && strings.HasPrefix(source[i:], "}}"):
+			text.WriteByte(c)
+			i++
+		case c == '}':
+			return nil, fmt.Errorf("模板第 %d 个字符处多余的 }", i+1)
+		case c == '{':
+			end := strings.IndexByte(source[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("模板第 %d 个字符处的 { 没有对应的 }", i+1)
+			}
+			body := source[i+1 : i+end]
+			name, format, _ := strings.Cut(body, ":")
+			var fields []string
+			for _, field := range strings.Split(name, "|") {
+				field = strings.TrimSpace(field)
+				if field == "" {
+					return nil, fmt.Errorf("模板中的 {%s} 缺少字段名", body)
+				}
+				fields = append(fields, field)
+			}
+			if text.Len() > 0 {
+				t.parts = append(t.parts, templatePart{text: text.String()})
+				text.Reset()
+			}
+			t.parts = append(t.parts, templatePart{fields: fields, format: format})
+			i += end
+		default:
+			text.WriteByte(c)
+		}
+	}
+	if text.Len() > 0 {
+		t.parts = append(t.parts, templatePart{text: text.String()})
+	}
+	return t, nil
+}
+
+// Fields 返回模板中用到的所有字段（含候选字段），按出现顺序去重
+func (t *Template) Fields() []string {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, part := range t.parts {
+		for _, field := range part.fields {
+			if !seen[field] {
+				seen[field] = true
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// Execute 依次填充占位符生成文件名。lookup 返回 ErrFieldMissing 时尝试下一个候选字段，
+// 所有候选字段都没有值或 lookup 返回其他错误时生成失败
+func (t *Template) Execute(lookup func(field, format string) (string, error)) (string, error) {
+	var result strings.Builder
+	for _, part := range t.parts {
+		if part.fields == nil {
+			result.WriteString(part.text)
+			continue
+		}
+		value, err := "", ErrFieldMissing
+		for _, field := range part.fields {
+			value, err = lookup(field, part.format)
+			if !errors.Is(err, ErrFieldMissing) {
+				break
+			}
+		}
+		if errors.Is(err, ErrFieldMissing) {
+			return "", fmt.Errorf("{%s} 没有值", strings.Join(part.fields, "|"))
+		}
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(value)
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestParseTemplate(t *testing.T) {
+	tp, err := ParseTemplate("{{x}}_{a|b:03}-{c:15:04}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tp.Execute(func(field, format string) (string, error) {
+		switch field {
+		case "b":
+			return "B" + format, nil
+		case "c":
+			return "C" + format, nil
+		}
+		return "", ErrFieldMissing
+	})
+	if err != nil || got != "{x}_B03-C15:04" {
+		t.Fatalf("模板输出为 %q, %v，应为 %q", got, err, "{x}_B03-C15:04")
+	}
+	for _, bad := range []string{"{a", "a}", "{}", "{|x}"} {
+		if _, err := ParseTemplate(bad); err == nil {
+			t.Errorf("模板 %q 应报错", bad)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gosimple/unidecode"
+	"golang.org/x/text/unicode/norm"
+)
+
+//go:generate go run scriptdata/gen.go
+
+// ScriptConversion 文件名的文字转换方式，用于上传到只接受 ASCII 文件名的系统等场合
+type ScriptConversion string
+
+const (
+	ConvertPinyin         ScriptConversion = "pinyin"          // 汉字转为不带声调的全拼，ü 写作 v，如 绿色 → lvse
+	ConvertPinyinInitials ScriptConversion = "pinyin_initials" // 汉字转为拼音首字母，如 中国 → zg
+	ConvertPinyinTone     ScriptConversion = "pinyin_tone"     // 汉字转为带声调符号的拼音，如 中国 → zhōngguó
+	ConvertToSimplified   ScriptConversion = "t2s"             // 繁体转为简体
+	ConvertToTraditional  ScriptConversion = "s2t"             // 简体转为繁体
+	ConvertToLatin        ScriptConversion = "latin"           // 汉字转为拼音，其他文字和带附加符号的字母转为相近的 ASCII 字母
+)
+
+// ScriptConversions 按界面显示顺序列出所有文字转换方式
+var ScriptConversions = []ScriptConversion{
+	ConvertPinyin, ConvertPinyinInitials, ConvertPinyinTone, ConvertToSimplified, ConvertToTraditional, ConvertToLatin,
+}
+
+var scriptConversionNames = map[ScriptConversion]string{
+	ConvertPinyin:         "汉字转拼音",
+	ConvertPinyinInitials: "汉字转拼音首字母",
+	ConvertPinyinTone:     "汉字转带声调拼音",
+	ConvertToSimplified:   "繁体转简体",
+	ConvertToTraditional:  "简体转繁体",
+	ConvertToLatin:        "全部转为拉丁字母（ASCII）",
+}
+
+// String 返回文字转换方式的中文名称
+func (c ScriptConversion) String() string {
+	if name, ok := scriptConversionNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知的文字转换(%s)", string(c))
+}
+
+// ParseScriptConversion 根据中文名称解析文字转换方式
+func ParseScriptConversion(name string) (ScriptConversion, error) {
+	for conversion, conversionName := range scriptConversionNames {
+		if conversionName == name {
+			return conversion, nil
+		}
+	}
+	return "", fmt.Errorf("未知的文字转换方式: %s", name)
+}
+
+// Validate 检查文字转换方式是否受支持
+func (c ScriptConversion) Validate() error {
+	if _, ok := scriptConversionNames[c]; !ok {
+		return fmt.Errorf("未知的文字转换方式: %s", string(c))
+	}
+	return nil
+}
+
+// 由 scriptdata/gen.go 用 ICU 生成的对照表，每行为“原字\t结果”，多音字只取一个常用读音，简繁按单字转换
+var (
+	//go:embed scriptdata/pinyin.txt
+	pinyinData string
+	//go:embed scriptdata/t2s.txt
+	toSimplifiedData string
+	//go:embed scriptdata/s2t.txt
+	toTraditionalData string
+)
+
+// scriptTables 解析后的对照表，第一次使用时才解析
+var scriptTables struct {
+	once          sync.Once
+	pinyin        map[rune]string
+	toSimplified  map[rune]rune
+	toTraditional map[rune]rune
+}
+
+// loadScriptTables 解析内嵌的对照表
+func loadScriptTables() {
+	scriptTables.once.Do(func() {
+		scriptTables.pinyin = make(map[rune]string)
+		scriptTables.toSimplified = make(map[rune]rune)
+		scriptTables.toTraditional = make(map[rune]rune)
+		parse := func(data string, add func(from rune, to string)) {
+			for _, line := range strings.Split(data, "\n") {
+				from, to, ok := strings.Cut(line, "\t")
+				if r, size := utf8.DecodeRuneInString(from); ok && size == len(from) && to != "" {
+					add(r, to)
+				}
+			}
+		}
+		parse(pinyinData, func(from rune, to string) { scriptTables.pinyin[from] = to })
+		parse(toSimplifiedData, func(from rune, to string) {
+			scriptTables.toSimplified[from], _ = utf8.DecodeRuneInString(to)
+		})
+		parse(toTraditionalData, func(from rune, to string) {
+			scriptTables.toTraditional[from], _ = utf8.DecodeRuneInString(to)
+		})
+	})
+}
+
+// ConvertScript 按指定方式转换文本。转为拼音时 separator 插在相邻汉字的拼音之间，非汉字原样保留；
+// 转为拉丁字母时结果只含 ASCII 字符，无法转写的字符（如表情符号）会被去掉，路径分隔符替换为 _
+func ConvertScript(text string, conversion ScriptConversion, separator string) string {
+	loadScriptTables()
+	switch conversion {
+	case ConvertToSimplified, ConvertToTraditional:
+		table := scriptTables.toSimplified
+		if conversion == ConvertToTraditional {
+			table = scriptTables.toTraditional
+		}
+		return strings.Map(func(r rune) rune {
+			if to, ok := table[r]; ok {
+				return to
+			}
+			return r
+		}, text)
+	case ConvertPinyin, ConvertPinyinInitials, ConvertPinyinTone:
+		return toPinyin(text, conversion, separator)
+	case ConvertToLatin:
+		return pathSeparatorReplacer.Replace(unidecode.Unidecode(toPinyin(text, ConvertPinyin, separator)))
+	}
+	return text
+}
+
+// toPinyin 把文本中的汉字逐字转为拼音
+func toPinyin(text string, style ScriptConversion, separator string) string {
+	var b strings.Builder
+	previousHan := false
+	for _, r := range text {
+		syllable, ok := scriptTables.pinyin[r]
+		if !ok {
+			b.WriteRune(r)
+			previousHan = false
+			continue
+		}
+		if previousHan {
+			b.WriteString(separator)
+		}
+		previousHan = true
+		if style == ConvertPinyinTone {
+			b.WriteString(syllable)
+			continue
+		}
+		syllable = plainPinyin(syllable)
+		if style == ConvertPinyinInitials {
+			syllable = syllable[:1]
+		}
+		b.WriteString(syllable)
+	}
+	return b.String()
+}
+
+// umlautReplacer 按输入法的习惯把 ü（含带声调的写法）写作 v
+var umlautReplacer = strings.NewReplacer("ü", "v", "ǖ", "v", "ǘ", "v", "ǚ", "v", "ǜ", "v")
+
+// plainPinyin 去掉拼音的声调符号，如 lǜ → lv
+func plainPinyin(syllable string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, norm.NFD.String(umlautReplacer.Replace(syllable)))
+}
@@ -0,0 +1,48 @@
+package utils
+
+import "testing"
+
+func TestConvertScript(t *testing.T) {
+	tests := []struct {
+		text       string
+		conversion ScriptConversion
+		separator  string
+		want       string
+	}{
+		{"中国abc绿色", ConvertPinyin, "", "zhongguoabclvse"},
+		{"中国 人", ConvertPinyin, "_", "zhong_guo ren"},
+		{"中华人民共和国", ConvertPinyinInitials, "", "zhrmghg"},
+		{"中国", ConvertPinyinTone, "-", "zhōng-guó"},
+		{"臺灣電影", ConvertToSimplified, "", "台湾电影"},
+		{"发电机", ConvertToTraditional, "", "發電機"},
+		{"Привет мир_中国_Café 😀/x", ConvertToLatin, "", "Privet mir_zhongguo_Cafe _x"},
+		{"こんにちは", ConvertToLatin, "", "konnichiha"},
+	}
+	for _, tt := range tests {
+		if got := ConvertScript(tt.text, tt.conversion, tt.separator); got != tt.want {
+			t.Errorf("%s（%s）结果为 %q，应为 %q", tt.text, tt.conversion, got, tt.want)
+		}
+	}
+	for _, conversion := range ScriptConversions {
+		if parsed, err := ParseScriptConversion(conversion.String()); err != nil || parsed != conversion {
+			t.Errorf("解析 %q 得到 %v, %v", conversion.String(), parsed, err)
+		}
+	}
+}
+
+func TestRuleChainScriptRule(t *testing.T) {
+	chain := RuleChain{Rules: []RenameRule{
+		{Type: RuleScript, Script: ConvertPinyin, Separator: "_"},
+		{Type: RuleCase, Case: CaseTitle},
+	}}
+	names, err := chain.Apply([]string{"第一章 开始.MP3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Di_Yi_Zhang Kai_Shi.MP3"; names[0] != want {
+		t.Fatalf("改名结果为 %q，应为 %q", names[0], want)
+	}
+	if err := (RuleChain{Rules: []RenameRule{{Type: RuleScript, Script: "x"}}}).Validate(); err == nil {
+		t.Fatal("未知的文字转换方式应报错")
+	}
+}
@@ -0,0 +1,65 @@
+//go:build ignore
+
+// gen 用 ICU 的 uconv 工具生成汉字拼音和简繁对照表，在 utils 目录下运行 go generate 重新生成
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// hanRanges 需要生成对照表的汉字范围：扩展 A 区、基本区和兼容汉字
+var hanRanges = [][2]rune{{0x3400, 0x4DBF}, {0x4E00, 0x9FFF}, {0xF900, 0xFAFF}}
+
+// pinyinPattern 有效的拼音读音，只含字母和声调符号
+var pinyinPattern = regexp.MustCompile(`^[a-zü\x{00C0}-\x{024F}\x{1E00}-\x{1EFF}]+$`)
+
+func main() {
+	var chars []string
+	for _, r := range hanRanges {
+		for c := r[0]; c <= r[1]; c++ {
+			chars = append(chars, string(c))
+		}
+	}
+	tables := []struct {
+		file, transform string
+		valid           func(string) bool
+	}{
+		{"scriptdata/pinyin.txt", "Han-Latin", pinyinPattern.MatchString},
+		{"scriptdata/t2s.txt", "Hant-Hans", func(s string) bool { return utf8.RuneCountInString(s) == 1 }},
+		{"scriptdata/s2t.txt", "Hans-Hant", func(s string) bool { return utf8.RuneCountInString(s) == 1 }},
+	}
+	for _, table := range tables {
+		if err := generate(table.file, table.transform, chars, table.valid); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// generate 逐字转换并只保留有变化且有效的结果，每行为“原字\t结果”
+func generate(file, transform string, chars []string, valid func(string) bool) error {
+	cmd := exec.Command("uconv", "-f", "utf-8", "-t", "utf-8", "-x", transform)
+	cmd.Stdin = strings.NewReader(strings.Join(chars, "\n") + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("uconv %s 失败: %v", transform, err)
+	}
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for i := 0; scanner.Scan(); i++ {
+		if i >= len(chars) {
+			return fmt.Errorf("uconv %s 输出的行数多于输入", transform)
+		}
+		if result := scanner.Text(); result != chars[i] && valid(result) {
+			lines = append(lines, chars[i]+"\t"+result)
+		}
+	}
+	return os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// readCSVRows 读取逗号分隔的文本表格
+func readCSVRows(path, sheet string) ([][]string, error) {
+	return readDelimitedRows(path, ',')
+}
+
+// readTSVRows 读取制表符分隔的文本表格
+func readTSVRows(path, sheet string) ([][]string, error) {
+	return readDelimitedRows(path, '\t')
+}
+
+// readDelimitedRows 自动识别编码后按分隔符读取文本表格
+func readDelimitedRows(path string, comma rune) ([][]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取表格文件失败: %v", err)
+	}
+	text, err := decodeText(content)
+	if err != nil {
+		return nil, fmt.Errorf("识别文件编码失败: %v", err)
+	}
+
+	r := csv.NewReader(bytes.NewReader(text))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	var rows [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析表格文件失败: %v", err)
+		}
+		rows = append(rows, record)
+	}
+	return rows, nil
+}
+
+// decodeText 识别文本编码并转为 UTF-8：带 BOM 时按 BOM 判断，
+// 否则是合法的 UTF-8 就按 UTF-8 处理，都不是时按 GB18030（兼容 GBK）解码
+func decodeText(content []byte) ([]byte, error) {
+	var enc encoding.Encoding
+	switch {
+	case bytes.HasPrefix(content, []byte{0xEF, 0xBB, 0xBF}):
+		return content[3:], nil
+	case bytes.HasPrefix(content, []byte{0xFF, 0xFE}):
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(content, []byte{0xFE, 0xFF}):
+		enc = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case utf8.Valid(content):
+		return content, nil
+	default:
+		enc = simplifiedchinese.GB18030
+	}
+	return enc.NewDecoder().Bytes(content)
+}
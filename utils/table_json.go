@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// orderedObject 保留键顺序的对象，对象数组的列按键第一次出现的顺序排列
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// readJSONRows 读取 JSON 数组。数组元素可以是对象（键作为标题行）、
+// 数组（第一个数组作为标题行）或单个值（作为只有一列的表格）；
+// 顶层是对象时读取其中第一个数组类型的字段
+func readJSONRows(path, sheet string) ([][]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取JSON文件失败: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(bytes.TrimPrefix(content, []byte{0xEF, 0xBB, 0xBF})))
+	decoder.UseNumber()
+	value, err := decodeJSONValue(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("解析JSON文件失败: %v", err)
+	}
+	return tableFromValue(value)
+}
+
+// decodeJSONValue 逐个读取 JSON 标记，对象按键的原始顺序保存
+func decodeJSONValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '[':
+			items := []interface{}{}
+			for decoder.More() {
+				item, err := decodeJSONValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+			_, err := decoder.Token()
+			return items, err
+		case '{':
+			object := &orderedObject{values: make(map[string]interface{})}
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				key := keyToken.(string)
+				value, err := decodeJSONValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := object.values[key]; !ok {
+					object.keys = append(object.keys, key)
+				}
+				object.values[key] = value
+			}
+			_, err := decoder.Token()
+			return object, err
+		}
+	case nil:
+		return "", nil
+	}
+	return fmt.Sprint(token), nil
+}
+
+// readYAMLRows 读取 YAML 数组，支持的结构与 JSON 相同
+func readYAMLRows(path, sheet string) ([][]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取YAML文件失败: %v", err)
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return nil, fmt.Errorf("解析YAML文件失败: %v", err)
+	}
+	if len(node.Content) == 0 {
+		return nil, fmt.Errorf("YAML文件为空")
+	}
+	return tableFromValue(yamlNodeValue(node.Content[0]))
+}
+
+// yamlNodeValue 把 YAML 节点转换为与 JSON 相同的结构
+func yamlNodeValue(node *yaml.Node) interface{} {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return yamlNodeValue(node.Alias)
+	case yaml.SequenceNode:
+		items := make([]interface{}, len(node.Content))
+		for i, child := range node.Content {
+			items[i] = yamlNodeValue(child)
+		}
+		return items
+	case yaml.MappingNode:
+		object := &orderedObject{values: make(map[string]interface{})}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if _, ok := object.values[key]; !ok {
+				object.keys = append(object.keys, key)
+			}
+			object.values[key] = yamlNodeValue(node.Content[i+1])
+		}
+		return object
+	}
+	if node.Tag == "!!null" {
+		return ""
+	}
+	return node.Value
+}
+
+// tableFromValue 把解析后的数组转换为表格行，第一行为标题行
+func tableFromValue(value interface{}) ([][]string, error) {
+	if object, ok := value.(*orderedObject); ok {
+		for _, key := range object.keys {
+			if items, ok := object.values[key].([]interface{}); ok {
+				value = items
+				break
+			}
+		}
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("文件内容应为数组，或包含数组字段的对象")
+	}
+	if len(items) == 0 {
+		return [][]string{}, nil
+	}
+
+	switch items[0].(type) {
+	case *orderedObject:
+		// 对象数组：所有对象的键合并为标题行
+		var header []string
+		columns := make(map[string]int)
+		for i, item := range items {
+			object, ok := item.(*orderedObject)
+			if !ok {
+				return nil, fmt.Errorf("第 %d 项不是对象", i+1)
+			}
+			for _, key := range object.keys {
+				if _, ok := columns[key]; !ok {
+					columns[key] = len(header)
+					header = append(header, key)
+				}
+			}
+		}
+		rows := [][]string{header}
+		for i, item := range items {
+			object := item.(*orderedObject)
+			row := make([]string, len(header))
+			for _, key := range object.keys {
+				cell, err := tableCell(object.values[key])
+				if err != nil {
+					return nil, fmt.Errorf("第 %d 项的 %s: %v", i+1, key, err)
+				}
+				row[columns[key]] = cell
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	case []interface{}:
+		// 数组的数组：与表格相同，第一个数组为标题行
+		rows := make([][]string, len(items))
+		for i, item := range items {
+			values, ok := item.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("第 %d 项不是数组", i+1)
+			}
+			rows[i] = make([]string, len(values))
+			for j, v := range values {
+				cell, err := tableCell(v)
+				if err != nil {
+					return nil, fmt.Errorf("第 %d 项第 %d 个值: %v", i+1, j+1, err)
+				}
+				rows[i][j] = cell
+			}
+		}
+		return rows, nil
+	default:
+		// 单个值的数组：作为只有一列的表格，补一个标题行
+		rows := [][]string{{"内容"}}
+		for i, item := range items {
+			cell, err := tableCell(item)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 项: %v", i+1, err)
+			}
+			rows = append(rows, []string{cell})
+		}
+		return rows, nil
+	}
+}
+
+// tableCell 单元格只能是简单值
+func tableCell(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("单元格的值不能是对象或数组")
+}
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// odsTableNS 和 odsTextNS OpenDocument 表格和文本的命名空间
+const (
+	odsTableNS = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odsTextNS  = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+)
+
+// readODSRows 读取 LibreOffice 等软件保存的 .ods 表格中的指定工作表
+func readODSRows(path, sheet string) ([][]string, error) {
+	var rows [][]string
+	err := withODSContent(path, func(r io.Reader) error {
+		var err error
+		rows, err = parseODSContent(r, sheet)
+		return err
+	})
+	return rows, err
+}
+
+// listODSSheets 列出 .ods 表格中的工作表
+func listODSSheets(path string) ([]string, error) {
+	var sheets []string
+	err := withODSContent(path, func(r io.Reader) error {
+		decoder := xml.NewDecoder(r)
+		for {
+			token, err := decoder.Token()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if t, ok := token.(xml.StartElement); ok && t.Name.Space == odsTableNS && t.Name.Local == "table" {
+				sheets = append(sheets, xmlAttr(t, "name"))
+				if err := decoder.Skip(); err != nil {
+					return err
+				}
+			}
+		}
+	})
+	return sheets, err
+}
+
+// withODSContent 打开 .ods 文件中的 content.xml 交给 fn 处理
+func withODSContent(path string, fn func(r io.Reader) error) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("打开ODS文件失败: %v", err)
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		if file.Name != "content.xml" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("读取ODS文件失败: %v", err)
+		}
+		defer rc.Close()
+		if err := fn(rc); err != nil {
+			return fmt.Errorf("解析ODS文件失败: %v", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("ODS文件中缺少 content.xml")
+}
+
+// parseODSContent 解析 content.xml 中名为 sheet 的工作表，sheet 为空时解析第一个工作表。
+// 重复的行和单元格按 number-rows-repeated、number-columns-repeated 展开，
+// 末尾为填满整张表而重复上百万次的空行和空单元格不会展开
+func parseODSContent(r io.Reader, sheet string) ([][]string, error) {
+	decoder := xml.NewDecoder(r)
+	var rows [][]string
+	var row []string
+	var cell strings.Builder
+	var inTable, found, inCell bool
+	var paragraphs int
+	var rowRepeat, cellRepeat, pendingRows, pendingCells int
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Space == odsTableNS && t.Name.Local == "table":
+				// 跳过其他工作表以及工作表中嵌套的子表
+				if inTable || (sheet != "" && xmlAttr(t, "name") != sheet) {
+					if err := decoder.Skip(); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				inTable, found = true, true
+				rows = [][]string{}
+			case !inTable:
+			case t.Name.Space == odsTableNS && t.Name.Local == "table-row":
+				row = nil
+				pendingCells = 0
+				rowRepeat = odsRepeat(t, "number-rows-repeated")
+			case t.Name.Space == odsTableNS && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell"):
+				inCell = true
+				paragraphs = 0
+				cell.Reset()
+				cellRepeat = odsRepeat(t, "number-columns-repeated")
+			case inCell && t.Name.Space == odsTextNS && t.Name.Local == "p":
+				if paragraphs > 0 {
+					cell.WriteByte('\n')
+				}
+				paragraphs++
+			case inCell && t.Name.Space == odsTextNS && t.Name.Local == "s":
+				cell.WriteString(strings.Repeat(" ", odsRepeat(t, "c")))
+			case inCell && t.Name.Space == odsTextNS && t.Name.Local == "tab":
+				cell.WriteByte('\t')
+			case inCell && t.Name.Space == odsTextNS && t.Name.Local == "line-break":
+				cell.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inCell && paragraphs > 0 {
+				cell.Write(t)
+			}
+		case xml.EndElement:
+			switch {
+			case !inTable:
+			case t.Name.Space == odsTableNS && t.Name.Local == "table":
+				return rows, nil
+			case t.Name.Space == odsTableNS && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell"):
+				inCell = false
+				value := cell.String()
+				if value == "" {
+					pendingCells += cellRepeat
+					continue
+				}
+				for ; pendingCells > 0; pendingCells-- {
+					row = append(row, "")
+				}
+				for i := 0; i < cellRepeat; i++ {
+					row = append(row, value)
+				}
+			case t.Name.Space == odsTableNS && t.Name.Local == "table-row":
+				if len(row) == 0 {
+					pendingRows += rowRepeat
+					continue
+				}
+				for ; pendingRows > 0; pendingRows-- {
+					rows = append(rows, nil)
+				}
+				for i := 0; i < rowRepeat; i++ {
+					rows = append(rows, append([]string(nil), row...))
+				}
+			}
+		}
+	}
+	if !found && sheet != "" {
+		return nil, fmt.Errorf("ODS文件中没有名为 %s 的工作表", sheet)
+	}
+	return rows, nil
+}
+
+// xmlAttr 读取元素的属性值，不区分命名空间
+func xmlAttr(t xml.StartElement, name string) string {
+	for _, attr := range t.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// odsRepeat 读取表示重复次数的属性，缺省为 1
+func odsRepeat(t xml.StartElement, name string) int {
+	if n, err := strconv.Atoi(xmlAttr(t, name)); err == nil && n > 0 {
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testODSContent 两个工作表的 content.xml，第一个工作表含重复的空行、重复的单元格和连续空格，
+// 末尾是 LibreOffice 常见的覆盖整张表的空行
+const testODSContent = `<?xml version="1.0"?><office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"><office:body><office:spreadsheet><table:table table:name="S1">
+<table:table-row><table:table-cell><text:p>旧</text:p></table:table-cell><table:table-cell><text:p>新</text:p></table:table-cell></table:table-row>
+<table:table-row table:number-rows-repeated="2"><table:table-cell table:number-columns-repeated="3"/></table:table-row>
+<table:table-row><table:table-cell><text:p>a<text:s text:c="2"/>b</text:p></table:table-cell><table:table-cell table:number-columns-repeated="2"><text:p>x</text:p></table:table-cell><table:table-cell table:number-columns-repeated="1000"/></table:table-row>
+<table:table-row table:number-rows-repeated="1048000"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+</table:table><table:table table:name="S2"><table:table-row><table:table-cell><text:p>zz</text:p></table:table-cell></table:table-row></table:table></office:spreadsheet></office:body></office:document-content>`
+
+func TestReadODSSheets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "table.ods")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("content.xml")
+	if err == nil {
+		_, err = w.Write([]byte(testODSContent))
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sheets, err := TableSheets(path)
+	if err != nil || !reflect.DeepEqual(sheets, []string{"S1", "S2"}) {
+		t.Fatalf("工作表为 %v, %v，应为 [S1 S2]", sheets, err)
+	}
+	rows, err := ReadTableRows(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"旧", "新"}, nil, nil, {"a  b", "x", "x"}}; !reflect.DeepEqual(rows, want) {
+		t.Fatalf("第一个工作表为 %q，应为 %q", rows, want)
+	}
+	rows, err = ReadTableSheet(path, "S2")
+	if err != nil || !reflect.DeepEqual(rows, [][]string{{"zz"}}) {
+		t.Fatalf("工作表 S2 为 %q, %v，应为 [[zz]]", rows, err)
+	}
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TableReader 读取一种格式的表格文件中指定工作表的所有行（含标题行），sheet 为空时读取第一个工作表，
+// 只有一个工作表的格式忽略 sheet。与 excelize 的 GetRows 一样，每行的长度可以不同
+type TableReader func(path, sheet string) ([][]string, error)
+
+// TableRowFunc 逐行读取表格时处理一行的函数，index 为该行的行号（从 1 开始），
+// 空行不会传入；返回错误时停止读取并把该错误返回给调用方
+type TableRowFunc func(index int, row []string) error
+
+// TableStreamer 逐行读取表格文件中的指定工作表，读到一行就交给 fn 处理，不保留已读过的行；
+// progress 可以为 nil
+type TableStreamer func(path, sheet string, fn TableRowFunc, progress ProgressCallback) error
+
+// SheetLister 列出表格文件中所有工作表的名称
+type SheetLister func(path string) ([]string, error)
+
+// tableReaders 扩展名（小写，带点）-> 读取方式
+var tableReaders = map[string]TableReader{
+	".xlsx": readXLSXRows,
+	".xlsm": readXLSXRows,
+	".csv":  readCSVRows,
+	".tsv":  readTSVRows,
+	".ods":  readODSRows,
+	".json": readJSONRows,
+	".yaml": readYAMLRows,
+	".yml":  readYAMLRows,
+}
+
+// tableStreamers 支持逐行读取的格式 -> 读取方式，其他格式先整体读取再逐行处理
+var tableStreamers = map[string]TableStreamer{
+	".xlsx": streamXLSXRows,
+	".xlsm": streamXLSXRows,
+}
+
+// errStopReading 逐行读取时提前结束，不作为错误返回
+var errStopReading = errors.New("stop reading")
+
+// sheetListers 支持多个工作表的格式 -> 列出工作表的方式
+var sheetListers = map[string]SheetLister{
+	".xlsx": listXLSXSheets,
+	".xlsm": listXLSXSheets,
+	".ods":  listODSSheets,
+}
+
+// RegisterTableReader 为指定扩展名注册读取方式，已有的读取方式（包括逐行读取方式）会被替换；
+// 只有一个工作表的格式 lister 传 nil
+func RegisterTableReader(ext string, reader TableReader, lister SheetLister) {
+	ext = strings.ToLower(ext)
+	tableReaders[ext] = reader
+	delete(tableStreamers, ext)
+	if lister != nil {
+		sheetListers[ext] = lister
+	} else {
+		delete(sheetListers, ext)
+	}
+}
+
+// TableExtensions 返回所有支持的表格扩展名，用于文件选择对话框的筛选
+func TableExtensions() []string {
+	exts := make([]string, 0, len(tableReaders))
+	for ext := range tableReaders {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// IsWorkbook 判断文件是否为可写回结果的 Excel 工作簿
+func IsWorkbook(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".xlsx" || ext == ".xlsm"
+}
+
+// TableSheets 返回表格文件中所有工作表的名称，只有一个工作表的格式返回 nil
+func TableSheets(path string) ([]string, error) {
+	lister, ok := sheetListers[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, nil
+	}
+	return lister(path)
+}
+
+// ReadTableRows 按扩展名选择读取方式，读取表格文件第一个工作表中的所有行（含标题行）
+func ReadTableRows(path string) ([][]string, error) {
+	return ReadTableSheet(path, "")
+}
+
+// ReadTableSheet 读取表格文件中指定工作表的所有行（含标题行），sheet 为空时读取第一个工作表
+func ReadTableSheet(path, sheet string) ([][]string, error) {
+	reader, err := tableReaderFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return reader(path, sheet)
+}
+
+// ReadTableHead 只读取工作表的前 n 行，用于识别标题和预览，大表格不必整个读完
+func ReadTableHead(path, sheet string, n int) ([][]string, error) {
+	var rows [][]string
+	err := StreamTableSheet(path, sheet, func(index int, row []string) error {
+		if index > n {
+			return errStopReading
+		}
+		for len(rows) < index-1 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// StreamTableSheet 逐行读取表格文件中的指定工作表，每读到一行非空的行就交给 fn 处理，
+// 读取过程中通过 progressCallback 报告进度。Excel 工作簿按行流式读取，不会一次载入整个工作表
+func StreamTableSheet(path, sheet string, fn TableRowFunc, progressCallback ...ProgressCallback) error {
+	var progress ProgressCallback
+	if len(progressCallback) > 0 {
+		progress = progressCallback[0]
+	}
+	reader, err := tableReaderFor(path)
+	if err != nil {
+		return err
+	}
+
+	if streamer, ok := tableStreamers[strings.ToLower(filepath.Ext(path))]; ok {
+		err = streamer(path, sheet, fn, progress)
+	} else {
+		err = streamRows(reader, path, sheet, fn, progress)
+	}
+	if err == errStopReading {
+		return nil
+	}
+	return err
+}
+
+// streamRows 整体读取后逐行交给 fn 处理，用于不支持逐行读取的格式
+func streamRows(reader TableReader, path, sheet string, fn TableRowFunc, progress ProgressCallback) error {
+	rows, err := reader(path, sheet)
+	if err != nil {
+		return err
+	}
+	for i, row := range rows {
+		if len(row) > 0 {
+			if err := fn(i+1, row); err != nil {
+				return err
+			}
+		}
+	}
+	if progress != nil {
+		progress(len(rows), len(rows), 100)
+	}
+	return nil
+}
+
+// tableReaderFor 检查表格文件是否存在，并按扩展名找到读取方式
+func tableReaderFor(path string) (TableReader, error) {
+	if path == "" {
+		return nil, fmt.Errorf("表格文件路径不能为空")
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("表格文件不存在: %s", path)
+	}
+	reader, ok := tableReaders[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("不支持的表格格式: %s，支持的格式有 %s", filepath.Ext(path), strings.Join(TableExtensions(), "、"))
+	}
+	return reader, nil
+}
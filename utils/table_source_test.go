@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// writeTableFile 在临时文件夹中写入指定扩展名的表格文件，返回文件路径
+func writeTableFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadExcelForRenameTextTables(t *testing.T) {
+	csv := "旧文件名,新文件名\n甲.mp3,\"乙,1.mp3\"\n"
+	gbk, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{"gbk.csv", gbk},
+		{"bom.csv", append([]byte{0xEF, 0xBB, 0xBF}, csv...)},
+		{"table.tsv", []byte("h1\th2\n甲.mp3\t乙,1.mp3\n")},
+		{"objects.json", []byte(`[{"旧文件名":"甲.mp3","新文件名":"乙,1.mp3"}]`)},
+		{"rows.json", []byte(`{"data":[["o","n"],["甲.mp3","乙,1.mp3"]]}`)},
+		{"table.yaml", []byte("- old: 甲.mp3\n  new: 乙,1.mp3\n")},
+	}
+	want := []ExcelData{{Row: 2, OldName: "甲.mp3", NewName: "乙,1.mp3"}}
+	for _, tt := range tests {
+		got, err := ReadExcelForRename(writeTableFile(t, tt.name, tt.content))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s 读取到 %+v，应为 %+v", tt.name, got, want)
+		}
+	}
+}
+
+func TestReadExcelForTTSJSONValues(t *testing.T) {
+	texts, err := ReadExcelForTTS(writeTableFile(t, "texts.json", []byte(`["你好", 12, true]`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"你好", "12", "true"}; !reflect.DeepEqual(texts, want) {
+		t.Fatalf("读取到 %v，应为 %v", texts, want)
+	}
+}
+
+func TestReadTableRowsRejectsUnknownFormat(t *testing.T) {
+	if _, err := ReadTableRows(writeTableFile(t, "table.doc", nil)); err == nil {
+		t.Fatal("不支持的格式应报错")
+	}
+}
@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxProgressStep 逐行读取 Excel 工作表时每隔多少行报告一次进度
+const xlsxProgressStep = 1000
+
+// readXLSXRows 读取 Excel 工作簿中的指定工作表，与 excelize 的 GetRows 一样，中间的空行保留为 nil
+func readXLSXRows(path, sheet string) ([][]string, error) {
+	var rows [][]string
+	err := streamXLSXRows(path, sheet, func(index int, row []string) error {
+		for len(rows) < index-1 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, row)
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// streamXLSXRows 用 excelize 的行迭代器逐行读取工作表，不会把整个工作表载入内存；
+// 进度的总行数取自工作表记录的使用范围，无法得知时总数和百分比为 0
+func streamXLSXRows(path, sheet string, fn TableRowFunc, progress ProgressCallback) error {
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return fmt.Errorf("打开Excel文件失败: %v", err)
+	}
+	defer f.Close()
+
+	sheetName := sheet
+	if sheetName == "" {
+		sheetName = f.GetSheetName(0)
+		if sheetName == "" {
+			return fmt.Errorf("Excel文件中没有工作表")
+		}
+	} else if index, _ := f.GetSheetIndex(sheetName); index < 0 {
+		return fmt.Errorf("Excel文件中没有名为 %s 的工作表", sheetName)
+	}
+
+	total := 0
+	if progress != nil {
+		total = xlsxRowCountHint(path, sheetName)
+	}
+	report := func(current int) {
+		if progress == nil {
+			return
+		}
+		if total == 0 {
+			progress(current, 0, 0)
+			return
+		}
+		if total < current {
+			total = current
+		}
+		progress(current, total, float64(current)/float64(total)*100)
+	}
+
+	rows, err := f.Rows(sheetName)
+	if err != nil {
+		return fmt.Errorf("读取工作表失败: %v", err)
+	}
+	defer rows.Close()
+
+	index := 0
+	for rows.Next() {
+		index++
+		row, err := rows.Columns()
+		if err != nil {
+			return fmt.Errorf("读取第 %d 行失败: %v", index, err)
+		}
+		if len(row) > 0 {
+			if err := fn(index, row); err != nil {
+				return err
+			}
+		}
+		if index%xlsxProgressStep == 0 {
+			report(index)
+		}
+	}
+	if err := rows.Error(); err != nil {
+		return fmt.Errorf("读取工作表失败: %v", err)
+	}
+	report(index)
+	return nil
+}
+
+// listXLSXSheets 列出 Excel 工作簿中的工作表
+func listXLSXSheets(path string) ([]string, error) {
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开Excel文件失败: %v", err)
+	}
+	defer f.Close()
+	return f.GetSheetList(), nil
+}
+
+// xlsxRowCountHint 从工作表 XML 开头的 dimension 元素读出最后一行的行号，只用于估算进度。
+// excelize 的 GetSheetDimension 会把整个工作表载入内存，这里直接在压缩包中查找，读到数据区就停止
+func xlsxRowCountHint(workbookPath, sheet string) int {
+	zr, err := zip.OpenReader(workbookPath)
+	if err != nil {
+		return 0
+	}
+	defer zr.Close()
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, file := range zr.File {
+		files[file.Name] = file
+	}
+
+	// 工作簿中工作表名称 -> 关系 ID -> 工作表文件
+	var relID string
+	scanXMLFile(files["xl/workbook.xml"], func(t xml.StartElement) bool {
+		if t.Name.Local == "sheet" && xmlAttr(t, "name") == sheet {
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "id" {
+					relID = attr.Value
+				}
+			}
+			return false
+		}
+		return true
+	})
+	var target string
+	scanXMLFile(files["xl/_rels/workbook.xml.rels"], func(t xml.StartElement) bool {
+		if t.Name.Local == "Relationship" && xmlAttr(t, "Id") == relID {
+			target = xmlAttr(t, "Target")
+			return false
+		}
+		return true
+	})
+	if relID == "" || target == "" {
+		return 0
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	var ref string
+	scanXMLFile(files[target], func(t xml.StartElement) bool {
+		switch t.Name.Local {
+		case "dimension":
+			ref = xmlAttr(t, "ref")
+			return false
+		case "sheetData":
+			return false
+		}
+		return true
+	})
+	// 只有一个单元格的范围通常是没有更新使用范围的程序写出的，不能作为行数
+	i := strings.LastIndex(ref, ":")
+	if i < 0 {
+		return 0
+	}
+	if _, row, err := excelize.CellNameToCoordinates(ref[i+1:]); err == nil {
+		return row
+	}
+	return 0
+}
+
+// scanXMLFile 依次把压缩包中 XML 文件的开始标签交给 fn，fn 返回 false 时停止
+func scanXMLFile(file *zip.File, fn func(t xml.StartElement) bool) {
+	if file == nil {
+		return
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return
+		}
+		if t, ok := token.(xml.StartElement); ok && !fn(t) {
+			return
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// moveToTrash 把文件移到当前用户的废纸篓（~/.Trash），重名时追加序号，返回文件在废纸篓中的路径
+func moveToTrash(path string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("找不到废纸篓: %v", err)
+	}
+	trashDir := filepath.Join(home, ".Trash")
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(filepath.Base(path), ext)
+	for n := 1; ; n++ {
+		target := filepath.Join(trashDir, filepath.Base(path))
+		if n > 1 {
+			target = filepath.Join(trashDir, fmt.Sprintf("%s %d%s", stem, n, ext))
+		}
+		if _, err := os.Lstat(target); err == nil {
+			continue
+		}
+		if err := os.Rename(path, target); err != nil {
+			return "", err
+		}
+		return target, nil
+	}
+}
+
+// restoreFromTrash 把废纸篓中的文件移回原位置
+func restoreFromTrash(trashPath, original string) error {
+	_, err := moveFile(trashPath, original, nil)
+	return err
+}
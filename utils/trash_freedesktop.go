@@ -0,0 +1,140 @@
+//go:build unix && !darwin
+
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// moveToTrash 按 freedesktop.org 回收站规范把文件移到回收站，返回文件在回收站中的路径。
+// 与主目录在同一文件系统时使用 $XDG_DATA_HOME/Trash，否则使用该文件系统根目录下的
+// .Trash/$uid 或 .Trash-$uid；只移动、不复制，无法移动时返回错误，不会永久删除
+func moveToTrash(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	trashDir, topdir, err := trashDirFor(abs)
+	if err != nil {
+		return "", fmt.Errorf("找不到可用的回收站: %v", err)
+	}
+	filesDir, infoDir := filepath.Join(trashDir, "files"), filepath.Join(trashDir, "info")
+	if err := os.MkdirAll(filesDir, 0700); err != nil {
+		return "", fmt.Errorf("创建回收站失败: %v", err)
+	}
+	if err := os.MkdirAll(infoDir, 0700); err != nil {
+		return "", fmt.Errorf("创建回收站失败: %v", err)
+	}
+
+	// 记录原位置的 .trashinfo 文件用独占方式创建，以此占用回收站中的文件名
+	location := abs
+	if topdir != "" {
+		location, _ = filepath.Rel(topdir, abs)
+	}
+	ext := filepath.Ext(abs)
+	stem := strings.TrimSuffix(filepath.Base(abs), ext)
+	for n := 1; ; n++ {
+		name := filepath.Base(abs)
+		if n > 1 {
+			name = fmt.Sprintf("%s.%d%s", stem, n, ext)
+		}
+		infoPath := filepath.Join(infoDir, name+".trashinfo")
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("写入回收站信息失败: %v", err)
+		}
+		_, err = fmt.Fprintf(f, "[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+			(&url.URL{Path: location}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		target := filepath.Join(filesDir, name)
+		if err == nil {
+			err = os.Rename(abs, target)
+		}
+		if err != nil {
+			os.Remove(infoPath)
+			return "", err
+		}
+		return target, nil
+	}
+}
+
+// restoreFromTrash 把回收站中的文件移回原位置，并删除对应的 .trashinfo 文件
+func restoreFromTrash(trashPath, original string) error {
+	if _, err := moveFile(trashPath, original, nil); err != nil {
+		return err
+	}
+	infoPath := filepath.Join(filepath.Dir(filepath.Dir(trashPath)), "info", filepath.Base(trashPath)+".trashinfo")
+	os.Remove(infoPath)
+	return nil
+}
+
+// trashDirFor 选择文件所在文件系统上的回收站，topdir 非空时表示使用该文件系统根目录下的回收站
+func trashDirFor(path string) (trashDir, topdir string, err error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	homeTrash := filepath.Join(dataHome, "Trash")
+
+	fileDev, err := deviceOf(filepath.Dir(path))
+	if err != nil {
+		return "", "", err
+	}
+	if homeDev, err := deviceOf(homeTrash); err == nil && homeDev == fileDev {
+		return homeTrash, "", nil
+	}
+
+	topdir = filepath.Dir(path)
+	for {
+		parent := filepath.Dir(topdir)
+		if parent == topdir {
+			break
+		}
+		if dev, err := deviceOf(parent); err != nil || dev != fileDev {
+			break
+		}
+		topdir = parent
+	}
+
+	// 管理员预先建立的 $topdir/.Trash 必须是设置了粘滞位的真实目录
+	uid := fmt.Sprint(os.Getuid())
+	shared := filepath.Join(topdir, ".Trash")
+	if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		return filepath.Join(shared, uid), topdir, nil
+	}
+	return filepath.Join(topdir, ".Trash-"+uid), topdir, nil
+}
+
+// deviceOf 返回路径所在的设备号，路径不存在时使用最近的已存在的上级目录
+func deviceOf(path string) (uint64, error) {
+	for {
+		info, err := os.Stat(path)
+		if err == nil {
+			st, ok := info.Sys().(*syscall.Stat_t)
+			if !ok {
+				return 0, fmt.Errorf("无法读取 %s 所在的设备", path)
+			}
+			return uint64(st.Dev), nil
+		}
+		parent := filepath.Dir(path)
+		if !os.IsNotExist(err) || parent == path {
+			return 0, err
+		}
+		path = parent
+	}
+}
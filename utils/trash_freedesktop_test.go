@@ -0,0 +1,41 @@
+//go:build unix && !darwin
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeleteMovesToTrashAndUndoRestores(t *testing.T) {
+	files := map[string]string{"old.txt": "OLD", "c.txt": "C"}
+	rows := []ExcelData{
+		{Operation: "delete", OldName: "old.txt"},
+		{OldName: "c.txt", NewName: "old.txt"},
+	}
+	_, results, dir := runTestPlan(t, files, rows, RenameOptions{})
+	checkStatuses(t, results, RenameSucceeded, RenameSucceeded)
+	checkTestFiles(t, dir, map[string]string{"old.txt": "C"})
+
+	trash := filepath.Join(os.Getenv("XDG_DATA_HOME"), "Trash")
+	checkTestFiles(t, filepath.Join(trash, "files"), map[string]string{"old.txt": "OLD"})
+	info, err := os.ReadFile(filepath.Join(trash, "info", "old.txt.trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Path=" + filepath.Join(dir, "old.txt"); !strings.Contains(string(info), want) {
+		t.Errorf("回收站信息为 %q，应包含 %q", info, want)
+	}
+
+	journal, err := LastRenameJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UndoRename(journal); err != nil {
+		t.Fatal(err)
+	}
+	checkTestFiles(t, dir, files)
+	checkTestFiles(t, trash, map[string]string{})
+}
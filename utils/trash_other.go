@@ -0,0 +1,18 @@
+//go:build !unix
+
+package utils
+
+import "errors"
+
+// errTrashUnsupported 当前系统不支持移到回收站，删除操作不会改为永久删除
+var errTrashUnsupported = errors.New("当前系统暂不支持移到回收站，未删除")
+
+// moveToTrash 当前系统不支持移到回收站
+func moveToTrash(path string) (string, error) {
+	return "", errTrashUnsupported
+}
+
+// restoreFromTrash 当前系统不支持移到回收站
+func restoreFromTrash(trashPath, original string) error {
+	return errTrashUnsupported
+}
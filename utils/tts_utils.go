@@ -13,6 +13,7 @@ type TTSConfig struct {
 	Voice string
 	Rate  string
 	Volume string
+	NameScript ScriptConversion // 生成文件名时的文字转换，如汉字转拼音，为空时文件名保留原文
 }
 
 // TextToSpeech 将文本转换为语音
@@ -43,6 +44,12 @@ func TextToSpeech(text, outputPath string, config TTSConfig) error {
 		return fmt.Errorf("无效的音量格式，应为'+N%'或'-N%'的格式，如'+0%'")
 	}
 
+	if config.NameScript != "" {
+		if err := config.NameScript.Validate(); err != nil {
+			return err
+		}
+	}
+
 	// 检查edge-tts命令是否可用
 	_, err := exec.LookPath("edge-tts")
 	if err != nil {
@@ -55,21 +62,16 @@ func TextToSpeech(text, outputPath string, config TTSConfig) error {
 	}
 
 	// 生成输出文件名 ‌在Windows系统中，文件名的最大长度为255个字符‌‌,使用128大多数情况下足够
+	// 空格替换为下划线，再按所有平台的规则修正，生成的音频可以直接复制到 Windows 或 U 盘上
+	// 设置了文字转换时先转换再截断，如汉字转拼音后文件名只含 ASCII 字符
 	maxLen := 128
-	if len(text) < maxLen {
-		maxLen = len(text)
+	fileName := text
+	if config.NameScript != "" {
+		fileName = ConvertScript(fileName, config.NameScript, "_")
 	}
-	fileName := strings.ReplaceAll(text[:maxLen], " ", "_")
-	fileName = strings.ReplaceAll(fileName, "/", "_")
-	fileName = strings.ReplaceAll(fileName, "\\", "_")
-	fileName = strings.ReplaceAll(fileName, "?", "_")
-	fileName = strings.ReplaceAll(fileName, "*", "_")
-	fileName = strings.ReplaceAll(fileName, ":", "_")
-	fileName = strings.ReplaceAll(fileName, "<", "_")
-	fileName = strings.ReplaceAll(fileName, ">", "_")
-	fileName = strings.ReplaceAll(fileName, "|", "_")
-	fileName = strings.ReplaceAll(fileName, "\"", "_")
-	outputFile := filepath.Join(outputPath, fileName+".mp3")
+	fileName = strings.ReplaceAll(truncateUTF8(fileName, maxLen), " ", "_")
+	fileName = SanitizeFileComponent(fileName+".mp3", TargetPlatforms)
+	outputFile := filepath.Join(outputPath, fileName)
 
 	// 构建edge-tts命令
 	cmd := exec.Command("edge-tts",
@@ -149,4 +151,30 @@ func BatchTextToSpeech(texts []string, outputPath string, config TTSConfig, prog
     }
     
     return nil
-}
\ No newline at end of file
+}
+
+// TableTextToSpeech 边读取文字转语音表格边转换，每读到一行文本就立即生成语音，不必等整个表格读完。
+// 进度按表格的行号报告，表格的总行数尚不知道时总数和百分比为 0
+func TableTextToSpeech(path string, mapping ColumnMapping, outputPath string, config TTSConfig, progressCallback ...ProgressCallback) error {
+	var progress ProgressCallback
+	if len(progressCallback) > 0 {
+		progress = progressCallback[0]
+	}
+	total := 0
+	readProgress := func(current, rows int, percentage float64) {
+		total = rows
+	}
+	return StreamTTSTable(path, mapping, func(row int, text string) error {
+		if progress != nil {
+			if total == 0 {
+				progress(row, 0, 0)
+			} else {
+				progress(row, total, float64(row)/float64(total)*100)
+			}
+		}
+		if err := TextToSpeech(text, outputPath, config); err != nil {
+			return fmt.Errorf("第 %d 行文本转换失败（文本内容：%s）：%v", row, text, err)
+		}
+		return nil
+	}, readProgress)
+}
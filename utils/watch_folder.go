@@ -0,0 +1,389 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultStableDuration 文件大小保持不变多久后才认为已经写完
+const DefaultStableDuration = 5 * time.Second
+
+// watchCheckInterval 检查等待中的文件是否已经稳定的间隔
+const watchCheckInterval = 500 * time.Millisecond
+
+// watchIgnoreFor 本程序改名生成的文件在这段时间内出现的事件会被忽略，避免把刚改好名的文件再处理一次
+const watchIgnoreFor = time.Minute
+
+// WatchConfig 监视文件夹的设置，映射表格和规则文件二选一
+type WatchConfig struct {
+	Folder   string        // 监视的文件夹，只处理其中直接放入的文件，不含子文件夹和隐藏文件
+	Table    string        // 映射表格：文件到达时按表格中的原文件名找到对应的新文件名，表格修改后自动重新读取
+	Rules    string        // 规则文件（规则链保存的 JSON）：按规则为到达的文件生成新文件名，编号接着已处理的文件计算
+	Stable   time.Duration // 文件大小和修改时间保持不变多久后才处理，为 0 时使用 DefaultStableDuration
+	Existing bool          // 启动时也处理文件夹中已有的文件
+	Options  RenameOptions // 冲突处理、匹配方式、目标平台等重命名选项，不在子文件夹中查找原文件
+	LogPath  string        // 日志文件，为空时写入配置目录下的 watch.log
+}
+
+// WatchLogFunc 监视过程中每写一条日志就调用一次，用于在界面上显示
+type WatchLogFunc func(line string)
+
+// FolderWatcher 监视一个文件夹，文件写完后自动按映射表格或规则改名，每一步操作都写入日志
+type FolderWatcher struct {
+	config  WatchConfig
+	watcher *fsnotify.Watcher
+	chain   RuleChain
+	logFile *os.File
+	onLog   WatchLogFunc
+
+	// 以下字段只在监视协程中使用
+	pending   map[string]*pendingFile // 文件名 -> 等待写完的文件
+	ignore    map[string]time.Time    // 本程序生成的文件名 -> 忽略其事件的截止时间
+	counter   int                     // 规则模式下已成功改名的文件数，用于接着编号
+	rows      []ExcelData             // 映射表格中的行
+	tableTime time.Time               // 读取映射表格时表格的修改时间
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// pendingFile 等待写完的文件
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	changed time.Time // 最近一次发现大小或修改时间变化的时间
+}
+
+// WatchLogPath 返回默认的监视日志位置
+func WatchLogPath() (string, error) {
+	dir, err := appConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "watch.log"), nil
+}
+
+// StartFolderWatcher 检查设置后开始监视文件夹，返回的监视器在调用 Stop 之前一直在后台运行
+func StartFolderWatcher(config WatchConfig, onLog WatchLogFunc) (*FolderWatcher, error) {
+	info, err := os.Stat(config.Folder)
+	if err != nil {
+		return nil, fmt.Errorf("读取监视文件夹失败: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("监视路径不是文件夹: %s", config.Folder)
+	}
+	if (config.Table == "") == (config.Rules == "") {
+		return nil, fmt.Errorf("请选择映射表格或规则文件中的一个")
+	}
+	if config.Stable <= 0 {
+		config.Stable = DefaultStableDuration
+	}
+	config.Options.Recursive = false
+
+	w := &FolderWatcher{
+		config:  config,
+		onLog:   onLog,
+		pending: make(map[string]*pendingFile),
+		ignore:  make(map[string]time.Time),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if config.Rules != "" {
+		if w.chain, err = LoadRuleChain(config.Rules); err != nil {
+			return nil, err
+		}
+	} else if err := w.loadTable(); err != nil {
+		return nil, err
+	}
+
+	if config.LogPath == "" {
+		if config.LogPath, err = WatchLogPath(); err != nil {
+			return nil, err
+		}
+		w.config.LogPath = config.LogPath
+	}
+	if err := os.MkdirAll(filepath.Dir(config.LogPath), 0755); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %v", err)
+	}
+	if w.logFile, err = os.OpenFile(config.LogPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+		return nil, fmt.Errorf("打开日志文件失败: %v", err)
+	}
+
+	if w.watcher, err = fsnotify.NewWatcher(); err != nil {
+		w.logFile.Close()
+		return nil, fmt.Errorf("创建文件监视失败: %v", err)
+	}
+	if err := w.watcher.Add(config.Folder); err != nil {
+		w.watcher.Close()
+		w.logFile.Close()
+		return nil, fmt.Errorf("监视文件夹失败: %v", err)
+	}
+
+	source := "映射表格 " + config.Table
+	if config.Rules != "" {
+		source = "规则文件 " + config.Rules
+	}
+	w.logf("开始监视 %s，使用%s，文件 %s 内不再变化后处理", config.Folder, source, config.Stable)
+	if config.Existing {
+		names, err := ListFolderFiles(config.Folder, "")
+		if err != nil {
+			w.logf("读取已有文件失败: %v", err)
+		}
+		now := time.Now()
+		for _, name := range names {
+			w.pending[name] = &pendingFile{size: -1, changed: now}
+		}
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Stop 停止监视并等待正在处理的文件完成，可以重复调用
+func (w *FolderWatcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+// Done 返回监视结束时关闭的通道
+func (w *FolderWatcher) Done() <-chan struct{} {
+	return w.done
+}
+
+// LogPath 返回日志文件的位置
+func (w *FolderWatcher) LogPath() string {
+	return w.config.LogPath
+}
+
+// logf 写一条带时间的日志，同时交给界面显示
+func (w *FolderWatcher) logf(format string, args ...interface{}) {
+	line := time.Now().Format("2006-01-02 15:04:05") + " " + fmt.Sprintf(format, args...)
+	fmt.Fprintln(w.logFile, line)
+	if w.onLog != nil {
+		w.onLog(line)
+	}
+}
+
+// run 监视协程：记录新到达的文件，定时检查哪些文件已经写完并统一处理
+func (w *FolderWatcher) run() {
+	defer close(w.done)
+	defer w.logFile.Close()
+	defer w.watcher.Close()
+
+	ticker := time.NewTicker(watchCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			w.logf("停止监视 %s", w.config.Folder)
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logf("监视出错: %v", err)
+		case now := <-ticker.C:
+			w.processStable(now)
+		}
+	}
+}
+
+// handleEvent 新建或移入的文件开始等待写完，写入时重新计时，被删除或移走时不再等待
+func (w *FolderWatcher) handleEvent(event fsnotify.Event) {
+	if filepath.Dir(event.Name) != filepath.Clean(w.config.Folder) {
+		return
+	}
+	name := filepath.Base(event.Name)
+	if strings.HasPrefix(name, ".") {
+		return
+	}
+	switch {
+	case event.Has(fsnotify.Create):
+		if until, ok := w.ignore[name]; ok && time.Now().Before(until) {
+			return
+		}
+		if _, ok := w.pending[name]; !ok {
+			w.logf("发现新文件 %s，等待写入完成", name)
+		}
+		w.pending[name] = &pendingFile{size: -1, changed: time.Now()}
+	case event.Has(fsnotify.Write):
+		if file, ok := w.pending[name]; ok {
+			file.changed = time.Now()
+		}
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		delete(w.pending, name)
+	}
+}
+
+// processStable 找出大小和修改时间在设定时间内没有变化的文件，交给 renameArrived 处理
+func (w *FolderWatcher) processStable(now time.Time) {
+	var ready []string
+	for name, file := range w.pending {
+		info, err := os.Lstat(filepath.Join(w.config.Folder, name))
+		if err != nil || info.IsDir() {
+			delete(w.pending, name)
+			continue
+		}
+		if info.Size() != file.size || !info.ModTime().Equal(file.modTime) {
+			file.size, file.modTime, file.changed = info.Size(), info.ModTime(), now
+			continue
+		}
+		if now.Sub(file.changed) >= w.config.Stable {
+			ready = append(ready, name)
+			delete(w.pending, name)
+		}
+	}
+	for name, until := range w.ignore {
+		if now.After(until) {
+			delete(w.ignore, name)
+		}
+	}
+	if len(ready) > 0 {
+		SortNatural(ready)
+		w.renameArrived(ready)
+	}
+}
+
+// renameArrived 为写完的文件生成新文件名并执行，结果写入日志和重命名历史。
+// 规则中有编号时逐个文件处理，只有改名成功的文件占用编号，失败的文件不会让后面的文件跳号
+func (w *FolderWatcher) renameArrived(names []string) {
+	if w.config.Rules != "" && w.chain.numbered() {
+		for _, name := range names {
+			w.renameBatch([]string{name})
+		}
+		return
+	}
+	w.renameBatch(names)
+}
+
+// renameBatch 为一批文件生成新文件名并执行，规则模式下按成功的行数推进编号
+func (w *FolderWatcher) renameBatch(names []string) {
+	var data []ExcelData
+	var err error
+	if w.config.Rules != "" {
+		data, err = w.ruleRenames(names)
+	} else {
+		data, err = w.tableRenames(names)
+	}
+	if err != nil {
+		w.logf("生成新文件名失败: %v", err)
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+
+	plan, err := PlanRename(w.config.Folder, data, w.config.Options)
+	if err != nil {
+		w.logf("生成重命名计划失败: %v", err)
+		return
+	}
+	results, err := ExecuteRenamePlan(plan)
+	if err != nil {
+		w.logf("执行重命名失败: %v", err)
+	}
+	if w.config.Rules != "" {
+		w.counter += CountRenameResults(results, RenameSucceeded)
+	}
+	for i, result := range results {
+		item := plan.Items[i]
+		line := fmt.Sprintf("[%s] %s", result.Op, item.OldName)
+		if item.NewName != "" {
+			line += " -> " + item.NewName
+		}
+		line += ": " + result.Status.String()
+		if result.Detail != "" {
+			line += "（" + result.Detail + "）"
+		}
+		w.logf("%s", line)
+		if result.Status == RenameSucceeded && item.NewPath != "" {
+			if rel, err := filepath.Rel(w.config.Folder, item.NewPath); err == nil {
+				w.ignore[strings.SplitN(rel, string(filepath.Separator), 2)[0]] = time.Now().Add(watchIgnoreFor)
+			}
+		}
+	}
+}
+
+// ruleRenames 按规则为文件生成新文件名，不符合筛选条件的文件不处理
+func (w *FolderWatcher) ruleRenames(names []string) ([]ExcelData, error) {
+	var matched []string
+	for _, name := range names {
+		if w.chain.Filter != "" {
+			if ok, _ := filepath.Match(w.chain.Filter, name); !ok {
+				w.logf("%s 不符合筛选条件 %s，未处理", name, w.chain.Filter)
+				continue
+			}
+		}
+		matched = append(matched, name)
+	}
+	newNames, err := w.chain.applyFrom(matched, w.counter)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]ExcelData, len(matched))
+	for i, name := range matched {
+		data[i] = ExcelData{Row: w.counter + i + 1, OldName: name, NewName: newNames[i]}
+	}
+	return data, nil
+}
+
+// tableRenames 在映射表格中找出这些文件对应的行，表格中没有的文件不处理
+func (w *FolderWatcher) tableRenames(names []string) ([]ExcelData, error) {
+	if info, err := os.Stat(w.config.Table); err == nil && !info.ModTime().Equal(w.tableTime) {
+		if err := w.loadTable(); err != nil {
+			return nil, err
+		}
+		w.logf("映射表格已更新，重新读取 %d 行", len(w.rows))
+	}
+
+	mode := w.config.Options.Match
+	arrived := make(map[string]string, len(names))
+	for _, name := range names {
+		arrived[matchKey(name, mode)] = name
+	}
+	var data []ExcelData
+	found := make(map[string]bool)
+	for _, row := range w.rows {
+		if name, ok := arrived[matchKey(normalizeRelPath(strings.TrimSpace(row.OldName)), mode)]; ok {
+			data = append(data, row)
+			found[name] = true
+		}
+	}
+	for _, name := range names {
+		if !found[name] {
+			w.logf("映射表格中没有 %s，未处理", name)
+		}
+	}
+	return data, nil
+}
+
+// loadTable 按自动识别或记住的列映射读取映射表格
+func (w *FolderWatcher) loadTable() error {
+	info, err := os.Stat(w.config.Table)
+	if err != nil {
+		return fmt.Errorf("读取映射表格失败: %v", err)
+	}
+	mapping, _, err := AutoColumnMapping(w.config.Table, MappingRename)
+	if err != nil {
+		return err
+	}
+	rows, err := ReadRenameTable(w.config.Table, mapping)
+	if err != nil {
+		return err
+	}
+	w.rows, w.tableTime = rows, info.ModTime()
+	return nil
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// numberingChain 把文件名替换为三位序号的规则链
+var numberingChain = RuleChain{Rules: []RenameRule{{Type: RuleNumber, Start: 1, Width: 3, Position: NumberReplace}}}
+
+func TestWatchRulesNumberOnlySucceededFiles(t *testing.T) {
+	useTempConfig(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"a.txt": "a", "c.txt": "c", "d.txt": "d"})
+	w := &FolderWatcher{
+		config: WatchConfig{Folder: dir, Rules: "rules.json"},
+		chain:  numberingChain,
+		ignore: make(map[string]time.Time),
+	}
+
+	// missing.txt 在改名前被移走，改名失败，不应占用编号
+	w.renameArrived([]string{"a.txt", "missing.txt", "c.txt"})
+	w.renameArrived([]string{"d.txt"})
+	checkTestFiles(t, dir, map[string]string{"001.txt": "a", "002.txt": "c", "003.txt": "d"})
+}
+
+func TestFolderWatcherRenamesArrivedFiles(t *testing.T) {
+	useTempConfig(t)
+	dir, settings := t.TempDir(), t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"old.txt": "old"})
+	rules := filepath.Join(settings, "rules.json")
+	if err := SaveRuleChain(rules, numberingChain); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := StartFolderWatcher(WatchConfig{
+		Folder:   dir,
+		Rules:    rules,
+		Stable:   10 * time.Millisecond,
+		Existing: true,
+		LogPath:  filepath.Join(settings, "watch.log"),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	waitForFile(t, filepath.Join(dir, "001.txt"))
+
+	// 启动后新放入的文件接着编号
+	writeTestFiles(t, dir, map[string]string{"new.txt": "new"})
+	waitForFile(t, filepath.Join(dir, "002.txt"))
+	w.Stop()
+	checkTestFiles(t, dir, map[string]string{"001.txt": "old", "002.txt": "new"})
+}
+
+// waitForFile 等待文件出现，超时后测试失败
+func waitForFile(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("等待 %s 超时", path)
+}